@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+// runArchiveSweeper periodically archives conversations that are about to expire
+// Used in on_expiry mode, where changes aren't written through to the archive
+func (b *Bot) runArchiveSweeper() {
+	interval := b.GetConfig().Archive.SweepInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			// Look two intervals ahead so nothing expires between sweeps
+			count, err := b.convManager.ArchiveExpiring(b.ctx, 2*interval)
+			if err != nil {
+				b.logger.Error().Err(err).Msg("Failed to archive expiring conversations")
+				continue
+			}
+			if count > 0 {
+				b.logger.Info().Int("conversations", count).Msg("Archived expiring conversations")
+			}
+		}
+	}
+}
+
+// rehydrateConversation restores an archived conversation when someone posts in its old thread
+// Returns nil if the thread has no archived conversation
+func (b *Bot) rehydrateConversation(ctx context.Context, s *discordgo.Session, guildID, threadID string) *conversation.Conversation {
+	if b.archive == nil {
+		return nil
+	}
+
+	conv, err := b.convManager.Rehydrate(ctx, guildID, threadID)
+	if errors.Is(err, storage.ErrNotArchived) {
+		return nil
+	} else if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to rehydrate conversation")
+		return nil
+	}
+
+	sendNotice(s, threadID, fmt.Sprintf("♻️ Restored this conversation from the archive (last active %s).", conv.UpdatedAt.Format("2006-01-02")))
+
+	b.logger.Info().
+		Str("guild", guildID).
+		Str("thread", threadID).
+		Msg("Conversation rehydrated from archive")
+
+	return conv
+}
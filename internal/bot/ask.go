@@ -8,7 +8,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 	"github.com/s33g/discord-prompter/internal/config"
 	"github.com/s33g/discord-prompter/internal/conversation"
-	"github.com/s33g/discord-prompter/internal/llm"
+	"github.com/s33g/discord-prompter/internal/moderation"
 )
 
 // handleAsk handles the /ask command - creates a new conversation thread
@@ -71,6 +71,20 @@ func (b *Bot) handleAsk(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
+	// Moderate the prompt before it is used for the title or the completion
+	subject := moderation.Subject{GuildID: i.GuildID, ChannelID: i.ChannelID, UserID: member.User.ID}
+	inputResult, err := b.moderation.Check(ctx, subject, moderation.StageInput, prompt)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Moderation check failed")
+		b.editInteractionError(s, i, "Failed to run moderation checks")
+		return
+	}
+	if inputResult.Blocked() {
+		b.editInteractionError(s, i, "Your prompt was blocked by moderation")
+		return
+	}
+	prompt = inputResult.Content
+
 	// Get system prompt
 	systemPrompt := ""
 	if systemPromptName != "" {
@@ -99,6 +113,13 @@ func (b *Bot) handleAsk(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	systemTokens, _ := tokenCounter.Count(systemPrompt, modelRef)
 	systemTokens += 4
 
+	// Make sure the prompt leaves room for a response in the model's window
+	limits := resolveContextLimits(cfg, guildCfg, modelRef)
+	if promptTokens+systemTokens+limits.reserve > limits.budget {
+		b.editInteractionError(s, i, fmt.Sprintf("Prompt is too long for %s (about %d tokens, limit %d)", modelRef, promptTokens+systemTokens, limits.budget-limits.reserve))
+		return
+	}
+
 	estimatedTokens := promptTokens + systemTokens + 1000 // Reserve for response
 
 	// Check token limits
@@ -114,42 +135,42 @@ func (b *Bot) handleAsk(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	// Generate thread title using LLM
-	b.logger.Info().Str("user", member.User.Username).Str("model", modelRef).Msg("Generating thread title")
-	title, err := b.llmRegistry.GenerateTitle(ctx, modelRef, prompt)
-	if err != nil {
-		b.logger.Warn().Err(err).Msg("Failed to generate title, using fallback")
-		title = prompt
-		if len(title) > 80 {
-			title = title[:77] + "..."
-		}
-	}
+	// Generate thread title with the (usually cheaper) title model alongside the main completion
+	titleModel := guildCfg.GetTitleModel(cfg.Defaults, modelRef)
+	b.logger.Info().Str("user", member.User.Username).Str("model", titleModel).Msg("Generating thread title")
+	titleCh := b.generateTitleAsync(ctx, guildCfg, member, titleModel, prompt)
 
 	// Get LLM response
 	b.logger.Info().Str("user", member.User.Username).Str("model", modelRef).Msg("Calling LLM")
 
 	messages := []conversation.Message{
 		{Role: "system", Content: systemPrompt, Tokens: systemTokens},
-		{Role: "user", Content: prompt, Tokens: promptTokens},
+		{Role: "user", Content: prompt, Tokens: promptTokens, AuthorID: member.User.ID, AuthorName: member.DisplayName()},
 	}
 
-	llmMessages := make([]llm.Message, len(messages))
-	for i, msg := range messages {
-		llmMessages[i] = llm.Message{Role: msg.Role, Content: msg.Content}
-	}
+	llmMessages := toLLMMessages(cfg, modelRef, messages)
 
-	provider, _, _ := cfg.ResolveModel(modelRef)
-	maxTokens := provider.DefaultMaxTokens
-	if maxTokens == 0 {
-		maxTokens = 2048
-	}
+	// Response gets whatever the model's window has left, up to the provider's cap
+	maxTokens := limits.maxTokens(systemTokens + promptTokens)
 
-	response, err := b.llmRegistry.Chat(ctx, modelRef, llmMessages, maxTokens, 0.7)
+	exchange, err := b.chat(ctx, moderation.Request{
+		Subject:     subject,
+		ModelRef:    modelRef,
+		Messages:    llmMessages,
+		MaxTokens:   maxTokens,
+		Temperature: replyTemperature,
+		SkipInput:   true, // Prompt was moderated above
+	})
 	if err != nil {
+		if msg, ok := moderationBlockedMessage(err); ok {
+			b.editInteractionError(s, i, msg)
+			return
+		}
 		b.logger.Error().Err(err).Msg("LLM request failed")
 		b.editInteractionError(s, i, fmt.Sprintf("Failed to get response from %s: %v", modelRef, err))
 		return
 	}
+	response := exchange.Response
 
 	if len(response.Choices) == 0 {
 		b.editInteractionError(s, i, "No response from model")
@@ -158,6 +179,14 @@ func (b *Bot) handleAsk(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 	assistantMessage := response.Choices[0].Message.Content
 
+	// Wait for the title
+	titleRes := <-titleCh
+	title := titleRes.title
+	if titleRes.err != nil || title == "" {
+		b.logger.Warn().Err(titleRes.err).Msg("Failed to generate title, using fallback")
+		title = fallbackTitle(prompt)
+	}
+
 	// Create thread
 	thread, err := s.MessageThreadStartComplex(i.ChannelID, i.ID, &discordgo.ThreadStart{
 		Name:                title,
@@ -179,14 +208,11 @@ func (b *Bot) handleAsk(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		Model:        modelRef,
 		SystemPrompt: systemPrompt,
 		Title:        title,
-		TokenCount:   response.Usage.TotalTokens,
+		TokenCount:   response.Usage.TotalTokens + titleRes.usage.TotalTokens,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	ttl := guildCfg.GetConversationTTL(cfg.Defaults)
-	b.convManager = conversation.NewManager(b.storage, ttl, cfg.Defaults.MessageHistoryLimit)
-
 	if err := b.convManager.Create(ctx, conv); err != nil {
 		b.logger.Error().Err(err).Msg("Failed to save conversation")
 	}
@@ -198,53 +224,32 @@ func (b *Bot) handleAsk(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		Tokens:  systemTokens,
 	})
 	b.convManager.AddMessage(ctx, i.GuildID, thread.ID, conversation.Message{
-		Role:      "user",
-		Content:   prompt,
-		Tokens:    promptTokens,
-		MessageID: i.ID,
-	})
-
-	// Post response in thread with buttons
-	_, err = s.ChannelMessageSendComplex(thread.ID, &discordgo.MessageSend{
-		Content: assistantMessage,
-		Components: []discordgo.MessageComponent{
-			discordgo.ActionsRow{
-				Components: []discordgo.MessageComponent{
-					discordgo.Button{
-						Label:    "🔄 Regenerate",
-						Style:    discordgo.PrimaryButton,
-						CustomID: "regenerate",
-					},
-					discordgo.Button{
-						Label:    "📋 Copy",
-						Style:    discordgo.SecondaryButton,
-						CustomID: "copy",
-					},
-					discordgo.Button{
-						Label:    "🗑️ Clear Context",
-						Style:    discordgo.DangerButton,
-						CustomID: "clear",
-					},
-					discordgo.Button{
-						Label:    "⚙️ Settings",
-						Style:    discordgo.SecondaryButton,
-						CustomID: "settings",
-					},
-				},
-			},
-		},
+		Role:       "user",
+		Content:    prompt,
+		Tokens:     promptTokens,
+		MessageID:  i.ID,
+		AuthorID:   member.User.ID,
+		AuthorName: member.DisplayName(),
+		SentAt:     time.Now().UnixMilli(),
 	})
 
+	// Post response in thread with buttons, noting what the conversation uses in case it has to be rebuilt
+	footer := conversation.Footer{Model: modelRef, Prompt: systemPromptName}
+	reply := replyMessage(modelRef, replyTemperature, exchange, assistantMessage)
+	layout := b.replyLayout(i.GuildID, reply, &footer)
+	ids, _, err := b.sendReply(s, i.GuildID, thread.ID, nil, layout, replyButtons(reply), nil)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to post message in thread")
 	}
 
 	// Save assistant message
-	b.convManager.AddMessage(ctx, i.GuildID, thread.ID, conversation.Message{
-		Role:    "assistant",
-		Content: assistantMessage,
-		Tokens:  response.Usage.CompletionTokens,
-	})
+	reply.SetDiscordIDs(ids)
+	b.convManager.AddMessage(ctx, i.GuildID, thread.ID, reply)
+
+	// Let the thread know if moderation changed or logged anything
+	if notice := moderationNotice(inputResult, exchange.Output); notice != "" {
+		sendNotice(s, thread.ID, notice)
+	}
 
 	// Edit original interaction to show thread link
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
@@ -270,6 +275,15 @@ func getStringOption(options []*discordgo.ApplicationCommandInteractionDataOptio
 	return ""
 }
 
+func getBoolOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) bool {
+	for _, opt := range options {
+		if opt.Name == name {
+			return opt.BoolValue()
+		}
+	}
+	return false
+}
+
 func (b *Bot) editInteractionError(s *discordgo.Session, i *discordgo.InteractionCreate, errMsg string) {
 	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
 		Content: stringPtr("❌ " + errMsg),
@@ -10,8 +10,10 @@ import (
 	"github.com/s33g/discord-prompter/internal/config"
 	"github.com/s33g/discord-prompter/internal/conversation"
 	"github.com/s33g/discord-prompter/internal/llm"
+	"github.com/s33g/discord-prompter/internal/moderation"
 	"github.com/s33g/discord-prompter/internal/ratelimit"
 	"github.com/s33g/discord-prompter/internal/rbac"
+	"github.com/s33g/discord-prompter/internal/search"
 	"github.com/s33g/discord-prompter/internal/storage"
 )
 
@@ -23,10 +25,14 @@ type Bot struct {
 	configWatcher *config.Watcher
 	configMu      sync.RWMutex
 	storage       *storage.Client
+	archive       storage.Archive
+	search        search.Index
 	llmRegistry   *llm.Registry
 	rbacManager   *rbac.Manager
 	rateLimiter   *ratelimit.Limiter
 	convManager   *conversation.Manager
+	moderation    *moderation.Pipeline
+	turns         *turnQueue
 	logger        zerolog.Logger
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -52,6 +58,12 @@ func New(cfg *config.Config, configPath string, logger zerolog.Logger) (*Bot, er
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
+	// Connect to the conversation archive (nil when disabled)
+	archive, err := storage.NewArchive(cfg.Archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to conversation archive: %w", err)
+	}
+
 	// Initialize LLM registry
 	llmRegistry, err := llm.NewRegistry(cfg)
 	if err != nil {
@@ -67,10 +79,19 @@ func New(cfg *config.Config, configPath string, logger zerolog.Logger) (*Bot, er
 		return nil, fmt.Errorf("failed to initialize rate limiter: %w", err)
 	}
 
-	// Initialize conversation manager
-	// Use default guild's settings for now (multi-guild support will vary per operation)
+	// Conversations use the defaults until the manager resolves per-guild settings below
 	defaultTTL := cfg.Defaults.ConversationTTL()
-	convManager := conversation.NewManager(storageClient, defaultTTL, cfg.Defaults.MessageHistoryLimit)
+
+	// Initialize search index (RediSearch when the module is loaded)
+	searchIndex, err := search.New(context.Background(), storageClient, defaultTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize search index: %w", err)
+	}
+	logger.Info().Str("backend", searchIndex.Name()).Msg("Search index ready")
+
+	convManager := conversation.NewManager(storageClient, defaultTTL, cfg.Defaults.MessageHistoryLimit).
+		WithArchive(archive, cfg.Archive.GetMode() == config.ArchiveWriteThrough).
+		WithSearch(searchIndex)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -79,15 +100,27 @@ func New(cfg *config.Config, configPath string, logger zerolog.Logger) (*Bot, er
 		config:      cfg,
 		configPath:  configPath,
 		storage:     storageClient,
+		archive:     archive,
+		search:      searchIndex,
 		llmRegistry: llmRegistry,
 		rbacManager: rbacManager,
 		rateLimiter: rateLimiter,
 		convManager: convManager,
+		turns:       newTurnQueue(),
 		logger:      logger,
 		ctx:         ctx,
 		cancel:      cancel,
 	}
 
+	// Resolve conversation TTLs and history limits per guild from the current (reloadable) config
+	bot.convManager.WithSettings(bot.conversationSettings)
+
+	// Initialize moderation pipeline (flags are posted through the bot's session)
+	bot.moderation, err = moderation.NewPipeline(cfg, moderation.NewRedisAuditor(storageClient), bot.postModerationFlag, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize moderation pipeline: %w", err)
+	}
+
 	// Register handlers
 	bot.registerHandlers()
 
@@ -122,6 +155,11 @@ func (b *Bot) Start() error {
 		b.configWatcher.Start()
 	}
 
+	// Start archiving conversations before they expire
+	if b.archive != nil && b.GetConfig().Archive.GetMode() == config.ArchiveOnExpiry {
+		go b.runArchiveSweeper()
+	}
+
 	b.logger.Info().Msg("Bot started successfully")
 	return nil
 }
@@ -147,6 +185,13 @@ func (b *Bot) Stop() error {
 		b.logger.Error().Err(err).Msg("Failed to close Redis connection")
 	}
 
+	// Close archive connection
+	if b.archive != nil {
+		if err := b.archive.Close(); err != nil {
+			b.logger.Error().Err(err).Msg("Failed to close archive connection")
+		}
+	}
+
 	b.logger.Info().Msg("Bot stopped")
 	return nil
 }
@@ -166,6 +211,11 @@ func (b *Bot) Reload(cfg *config.Config) error {
 		return fmt.Errorf("failed to reload RBAC manager: %w", err)
 	}
 
+	// Reload moderation rules
+	if err := b.moderation.Reload(cfg); err != nil {
+		return fmt.Errorf("failed to reload moderation pipeline: %w", err)
+	}
+
 	// Update config
 	b.config = cfg
 
@@ -173,6 +223,19 @@ func (b *Bot) Reload(cfg *config.Config) error {
 	return nil
 }
 
+// conversationSettings returns a guild's conversation TTL and history limit
+func (b *Bot) conversationSettings(guildID string) conversation.Settings {
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(guildID)
+	if err != nil {
+		return conversation.Settings{TTL: cfg.Defaults.ConversationTTL(), MaxMessages: cfg.Defaults.MessageHistoryLimit}
+	}
+	return conversation.Settings{
+		TTL:         guildCfg.GetConversationTTL(cfg.Defaults),
+		MaxMessages: guildCfg.GetMessageHistoryLimit(cfg.Defaults),
+	}
+}
+
 // GetConfig safely returns the current configuration
 func (b *Bot) GetConfig() *config.Config {
 	b.configMu.RLock()
@@ -184,6 +247,9 @@ func (b *Bot) GetConfig() *config.Config {
 func (b *Bot) registerHandlers() {
 	b.session.AddHandler(b.handleInteractionCreate)
 	b.session.AddHandler(b.handleMessageCreate)
+	b.session.AddHandler(b.handleMessageUpdate)
+	b.session.AddHandler(b.handleMessageDelete)
+	b.session.AddHandler(b.handleMessageDeleteBulk)
 	b.session.AddHandler(b.handleReady)
 }
 
@@ -238,6 +304,85 @@ func (b *Bot) registerCommands() error {
 			Name:        "reload",
 			Description: "Reload bot configuration (requires permission)",
 		},
+		{
+			Name:        "forks",
+			Description: "List forks of the current conversation",
+		},
+		{
+			Name:        "search",
+			Description: "Search conversation threads you can see",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "query",
+					Description: "Words to look for",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "conversations",
+			Description: "List your conversation threads",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "all",
+					Description: "List everyone's conversations on this server (requires permission)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "import",
+			Description: "Continue a conversation from a JSON transcript in a new thread",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "Exported JSON transcript or OpenAI-style messages array",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "transcript",
+					Description: "Paste a JSON transcript instead of attaching one",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "model",
+					Description: "Model to use (optional, uses the transcript's or the default)",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "system_prompt",
+					Description: "System prompt to use (optional, overrides the transcript's)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "export",
+			Description: "Export the current conversation as a file",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "format",
+					Description: "File format (default: Markdown)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Markdown", Value: conversation.ExportMarkdown},
+						{Name: "JSON", Value: conversation.ExportJSON},
+						{Name: "HTML", Value: conversation.ExportHTML},
+					},
+				},
+			},
+		},
+		{
+			Name: forkCommandName,
+			Type: discordgo.MessageApplicationCommand,
+		},
 	}
 
 	cfg := b.GetConfig()
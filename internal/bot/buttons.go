@@ -2,15 +2,80 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/s33g/discord-prompter/internal/config"
 	"github.com/s33g/discord-prompter/internal/conversation"
-	"github.com/s33g/discord-prompter/internal/llm"
+	"github.com/s33g/discord-prompter/internal/moderation"
+	"github.com/s33g/discord-prompter/internal/render"
 )
 
+// conversationButtons returns the action buttons attached to assistant replies
+func conversationButtons() []discordgo.MessageComponent {
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "🔄 Regenerate",
+					Style:    discordgo.PrimaryButton,
+					CustomID: "regenerate",
+				},
+				discordgo.Button{
+					Label:    "📋 Copy",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "copy",
+				},
+				discordgo.Button{
+					Label:    "↩️ Undo",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "undo",
+				},
+				discordgo.Button{
+					Label:    "🗑️ Clear Context",
+					Style:    discordgo.DangerButton,
+					CustomID: "clear",
+				},
+				discordgo.Button{
+					Label:    "⚙️ Settings",
+					Style:    discordgo.SecondaryButton,
+					CustomID: "settings",
+				},
+			},
+		},
+	}
+}
+
+// replyButtons returns the buttons for an assistant reply
+// A second row offers Continue if the reply was cut off and Download code if it has code blocks.
+func replyButtons(reply conversation.Message) []discordgo.MessageComponent {
+	buttons := conversationButtons()
+
+	var extra []discordgo.MessageComponent
+	if reply.Truncated() {
+		extra = append(extra, discordgo.Button{
+			Label:    "⏩ Continue",
+			Style:    discordgo.SuccessButton,
+			CustomID: "continue",
+		})
+	}
+	if len(render.CodeBlocks(reply.Content)) > 0 {
+		extra = append(extra, discordgo.Button{
+			Label:    "💾 Download code",
+			Style:    discordgo.SecondaryButton,
+			CustomID: "download",
+		})
+	}
+	if len(extra) == 0 {
+		return buttons
+	}
+
+	return append(buttons, discordgo.ActionsRow{Components: extra})
+}
+
 // handleButton routes button interactions to specific handlers
 func (b *Bot) handleButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	customID := i.MessageComponentData().CustomID
@@ -18,17 +83,37 @@ func (b *Bot) handleButton(s *discordgo.Session, i *discordgo.InteractionCreate)
 	switch customID {
 	case "regenerate":
 		b.handleRegenerateButton(s, i)
+	case "continue":
+		b.handleContinueButton(s, i)
+	case "undo":
+		b.handleUndoButton(s, i)
 	case "copy":
 		b.handleCopyButton(s, i)
+	case "download":
+		b.handleDownloadButton(s, i)
 	case "clear":
 		b.handleClearButton(s, i)
 	case "settings":
 		b.handleSettingsButton(s, i)
+	case "model_select":
+		b.handleModelSelect(s, i)
+	case "prompt_select":
+		b.handlePromptSelect(s, i)
+	case "strategy_select":
+		b.handleStrategySelect(s, i)
+	case "posting_select":
+		b.handlePostingSelect(s, i)
+	case "posters_select":
+		b.handlePostersSelect(s, i)
 	default:
 		if strings.HasPrefix(customID, "model:") {
 			b.handleModelSelect(s, i)
 		} else if strings.HasPrefix(customID, "prompt:") {
 			b.handlePromptSelect(s, i)
+		} else if strings.HasPrefix(customID, "variant:") {
+			b.handleVariantButton(s, i)
+		} else if strings.HasPrefix(customID, "conversations:") {
+			b.handleConversationsPage(s, i)
 		} else {
 			b.respondError(s, i, "Unknown button")
 		}
@@ -36,6 +121,7 @@ func (b *Bot) handleButton(s *discordgo.Session, i *discordgo.InteractionCreate)
 }
 
 // handleRegenerateButton regenerates the last response
+// The new response is kept as another variant of the same reply and edited into its message.
 func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	// Acknowledge the interaction
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -45,11 +131,20 @@ func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.Interact
 	ctx := context.Background()
 	threadID := i.ChannelID
 
+	// Wait for any turn in progress so the history isn't rewritten underneath it
+	unlock, err := b.lockConversation(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to lock conversation")
+		sendNotice(s, threadID, "❌ Another reply in this thread is still being written")
+		return
+	}
+	defer unlock()
+
 	// Load conversation
 	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to load conversation")
-		s.ChannelMessageSend(threadID, "❌ Failed to load conversation")
+		sendNotice(s, threadID, "❌ Failed to load conversation")
 		return
 	}
 
@@ -70,7 +165,7 @@ func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.Interact
 
 	// Check permissions
 	if !b.rbacManager.HasPermission(i.GuildID, member, "use_models") {
-		s.ChannelMessageSend(threadID, "❌ You don't have permission to use models")
+		sendNotice(s, threadID, "❌ You don't have permission to use models")
 		return
 	}
 
@@ -81,7 +176,7 @@ func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.Interact
 	// Check rate limits
 	rateResult, err := b.rateLimiter.CheckRateLimit(ctx, i.GuildID, member.User.ID, rateLimitCfg)
 	if err != nil || !rateResult.Allowed {
-		s.ChannelMessageSend(threadID, fmt.Sprintf("❌ Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
+		sendNotice(s, threadID, fmt.Sprintf("❌ Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
 		return
 	}
 
@@ -89,17 +184,20 @@ func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.Interact
 	messages, err := b.convManager.GetMessages(ctx, i.GuildID, threadID)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to load messages")
-		s.ChannelMessageSend(threadID, "❌ Failed to load message history")
+		sendNotice(s, threadID, "❌ Failed to load message history")
 		return
 	}
 
 	if len(messages) == 0 {
-		s.ChannelMessageSend(threadID, "❌ No message history found")
+		sendNotice(s, threadID, "❌ No message history found")
 		return
 	}
 
-	// Remove the last assistant message if it exists (we'll regenerate it)
-	if len(messages) > 0 && messages[len(messages)-1].Role == "assistant" {
+	// Regenerate the last reply from the history before it, or answer the last prompt if it has none
+	var reply conversation.Message
+	hasReply := messages[len(messages)-1].Role == "assistant"
+	if hasReply {
+		reply = messages[len(messages)-1]
 		messages = messages[:len(messages)-1]
 	}
 
@@ -113,82 +211,91 @@ func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.Interact
 	// Check token limits
 	tokenResult, err := b.rateLimiter.CheckTokenLimit(ctx, i.GuildID, member.User.ID, tokenLimitCfg, estimatedTokens)
 	if err != nil || !tokenResult.Allowed {
-		s.ChannelMessageSend(threadID, fmt.Sprintf("❌ Token limit exceeded. Resets in %d seconds.", tokenResult.SecondsToReset))
+		sendNotice(s, threadID, fmt.Sprintf("❌ Token limit exceeded. Resets in %d seconds.", tokenResult.SecondsToReset))
 		return
 	}
 
-	// Build context
-	maxContextTokens := guildCfg.GetMaxContextTokens(cfg.Defaults)
-	builder := conversation.NewContextBuilder(maxContextTokens, 1000)
-	contextMessages, _, err := builder.Build(messages, conv.SystemPrompt, conv.Model)
-	if err != nil {
+	// Build context using the conversation's context strategy
+	built, maxTokens, err := b.buildContext(ctx, s, cfg, guildCfg, conv, messages, len(messages), member)
+	if errors.Is(err, conversation.ErrContextFull) {
+		sendNotice(s, threadID, contextFullMessage)
+		return
+	} else if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to build context")
-		s.ChannelMessageSend(threadID, "❌ Failed to build context")
+		sendNotice(s, threadID, "❌ Failed to build context")
 		return
 	}
+	contextMessages := built.Messages
 
 	// Convert to LLM messages
-	llmMessages := make([]llm.Message, len(contextMessages))
-	for idx, msg := range contextMessages {
-		llmMessages[idx] = llm.Message{Role: msg.Role, Content: msg.Content}
-	}
+	llmMessages := toLLMMessages(cfg, conv.Model, contextMessages)
 
 	// Show typing
 	s.ChannelTyping(threadID)
 
-	// Get provider config
-	provider, _, _ := cfg.ResolveModel(conv.Model)
-	maxTokens := provider.DefaultMaxTokens
-	if maxTokens == 0 {
-		maxTokens = 2048
-	}
-
 	// Call LLM
-	response, err := b.llmRegistry.Chat(ctx, conv.Model, llmMessages, maxTokens, 0.7)
+	exchange, err := b.chat(ctx, moderation.Request{
+		Subject:     moderation.Subject{GuildID: i.GuildID, ChannelID: threadID, UserID: member.User.ID},
+		ModelRef:    conv.Model,
+		Messages:    llmMessages,
+		MaxTokens:   maxTokens,
+		Temperature: replyTemperature,
+		SkipInput:   true, // History was moderated when it was sent
+	})
 	if err != nil {
+		if msg, ok := moderationBlockedMessage(err); ok {
+			sendNotice(s, threadID, msg)
+			return
+		}
 		b.logger.Error().Err(err).Msg("LLM request failed")
-		s.ChannelMessageSend(threadID, fmt.Sprintf("❌ Failed to regenerate: %v", err))
+		sendNotice(s, threadID, fmt.Sprintf("❌ Failed to regenerate: %v", err))
 		return
 	}
+	response := exchange.Response
 
 	if len(response.Choices) == 0 {
-		s.ChannelMessageSend(threadID, "❌ No response from model")
+		sendNotice(s, threadID, "❌ No response from model")
 		return
 	}
 
 	assistantContent := response.Choices[0].Message.Content
+	generated := replyMessage(conv.Model, replyTemperature, exchange, assistantContent)
+
+	if hasReply {
+		// Show the new variant in place of the old one
+		reply.AddVariant(generated)
+		staleParts, err := b.showReply(s, i.GuildID, threadID, i.Message, &reply)
+		if err != nil {
+			b.logger.Error().Err(err).Msg("Failed to show regenerated reply")
+			sendNotice(s, threadID, "❌ Failed to update the reply")
+			return
+		}
+		// Only the reply changed, and compaction may have trimmed the history before it, so update it in place
+		if err := b.convManager.SetMessage(ctx, i.GuildID, threadID, -1, reply); err != nil {
+			b.logger.Error().Err(err).Msg("Failed to save regenerated reply")
+		}
+		for _, id := range staleParts {
+			s.ChannelMessageDelete(threadID, id)
+		}
+	} else {
+		// Post new response
+		ids, _, err := b.sendReply(s, i.GuildID, threadID, nil, b.replyLayout(i.GuildID, generated, nil), replyButtons(generated), nil)
+		if err != nil {
+			b.logger.Error().Err(err).Msg("Failed to send message")
+			return
+		}
 
-	// Post new response
-	msg, err := s.ChannelMessageSendComplex(threadID, &discordgo.MessageSend{
-		Content: assistantContent,
-		Components: []discordgo.MessageComponent{
-			discordgo.ActionsRow{
-				Components: []discordgo.MessageComponent{
-					discordgo.Button{Label: "🔄 Regenerate", Style: discordgo.PrimaryButton, CustomID: "regenerate"},
-					discordgo.Button{Label: "📋 Copy", Style: discordgo.SecondaryButton, CustomID: "copy"},
-					discordgo.Button{Label: "🗑️ Clear Context", Style: discordgo.DangerButton, CustomID: "clear"},
-					discordgo.Button{Label: "⚙️ Settings", Style: discordgo.SecondaryButton, CustomID: "settings"},
-				},
-			},
-		},
-	})
-
-	if err != nil {
-		b.logger.Error().Err(err).Msg("Failed to send message")
-		return
+		// Save assistant message
+		generated.SetDiscordIDs(ids)
+		b.convManager.AddMessage(ctx, i.GuildID, threadID, generated)
 	}
 
-	// Save assistant message
-	b.convManager.AddMessage(ctx, i.GuildID, threadID, conversation.Message{
-		Role:      "assistant",
-		Content:   assistantContent,
-		Tokens:    response.Usage.CompletionTokens,
-		MessageID: msg.ID,
-	})
-
 	// Update token count
-	conv.TokenCount += response.Usage.TotalTokens
-	b.convManager.Update(ctx, *conv)
+	b.convManager.IncrementTokenCount(ctx, i.GuildID, threadID, response.Usage.TotalTokens)
+
+	if notice := moderationNotice(exchange.Output); notice != "" {
+		sendNotice(s, threadID, notice)
+	}
 
 	b.logger.Info().
 		Str("user", member.User.Username).
@@ -200,23 +307,77 @@ func (b *Bot) handleRegenerateButton(s *discordgo.Session, i *discordgo.Interact
 
 // handleCopyButton sends the bot's message content as a code block
 func (b *Bot) handleCopyButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	// Get the message that the button is attached to
-	message := i.Message
-	content := message.Content
+	content := b.buttonReply(i)
+
+	// Send as ephemeral message in code block, or as a file if it doesn't fit
+	data := &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("```\n%s\n```", content),
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}
+	if utf8.RuneCountInString(data.Content) > maxMessageLength {
+		data.Content = "📋 This reply is too long for a code block, so here it is as a file."
+		data.Files = replyFiles(render.Layout{Attachment: content})
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: data,
+	})
+
+	b.logger.Debug().
+		Str("user", i.Member.User.Username).
+		Str("action", "copy").
+		Msg("Message copied")
+}
+
+// handleDownloadButton sends the code blocks of the bot's reply as files
+func (b *Bot) handleDownloadButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	blocks := render.CodeBlocks(b.buttonReply(i))
+	if len(blocks) == 0 {
+		b.respondError(s, i, "This reply has no code blocks")
+		return
+	}
+
+	// Discord allows up to 10 attachments per message
+	content := fmt.Sprintf("💾 %d code block(s) from this reply.", len(blocks))
+	if len(blocks) > maxAttachments {
+		content = fmt.Sprintf("💾 The first %d of %d code blocks from this reply.", maxAttachments, len(blocks))
+	}
+	var files []*discordgo.File
+	for n, block := range blocks[:min(len(blocks), maxAttachments)] {
+		files = append(files, &discordgo.File{
+			Name:        render.Filename(block, n+1, len(blocks)),
+			ContentType: "text/plain",
+			Reader:      strings.NewReader(block.Code + "\n"),
+		})
+	}
 
-	// Send as ephemeral message in code block
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("```\n%s\n```", content),
+			Content: content,
+			Files:   files,
 			Flags:   discordgo.MessageFlagsEphemeral,
 		},
 	})
 
 	b.logger.Debug().
 		Str("user", i.Member.User.Username).
-		Str("action", "copy").
-		Msg("Message copied")
+		Str("action", "download").
+		Int("files", len(files)).
+		Msg("Code downloaded")
+}
+
+// buttonReply returns the content of the reply a button is attached to
+// The stored reply is preferred since it has every part of a long reply; otherwise the message's
+// own text is used, without its metadata footer.
+func (b *Bot) buttonReply(i *discordgo.InteractionCreate) string {
+	if messages, err := b.convManager.GetMessages(context.Background(), i.GuildID, i.ChannelID); err == nil {
+		if idx := conversation.FindMessage(messages, i.Message.ID); idx >= 0 {
+			return messages[idx].Content
+		}
+	}
+
+	return postedText(i.Message)
 }
 
 // handleClearButton clears the message history while keeping conversation metadata
@@ -224,22 +385,29 @@ func (b *Bot) handleClearButton(s *discordgo.Session, i *discordgo.InteractionCr
 	ctx := context.Background()
 	threadID := i.ChannelID
 
+	// Wait for any turn in progress so its reply isn't added to the cleared history
+	unlock := b.deferAndLock(ctx, s, i)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
 	// Get member
 	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
 	if err != nil {
-		b.respondError(s, i, "Failed to get member info")
+		b.editInteractionError(s, i, "Failed to get member info")
 		return
 	}
 
 	// Check permissions (only the conversation owner or admins can clear)
 	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
 	if err != nil {
-		b.respondError(s, i, "Failed to load conversation")
+		b.editInteractionError(s, i, "Failed to load conversation")
 		return
 	}
 
 	if conv.UserID != member.User.ID && !b.rbacManager.HasPermission(i.GuildID, member, "manage_prompts") {
-		b.respondError(s, i, "You can only clear your own conversations")
+		b.editInteractionError(s, i, "You can only clear your own conversations")
 		return
 	}
 
@@ -247,21 +415,18 @@ func (b *Bot) handleClearButton(s *discordgo.Session, i *discordgo.InteractionCr
 	err = b.convManager.ClearMessages(ctx, i.GuildID, threadID)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to clear messages")
-		b.respondError(s, i, "Failed to clear conversation history")
+		b.editInteractionError(s, i, "Failed to clear conversation history")
 		return
 	}
 
-	// Reset token count
+	// Reset token count and compacted summary
 	conv.TokenCount = 0
+	conv.Summary = ""
 	b.convManager.Update(ctx, *conv)
 
 	// Respond
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "✅ Conversation history cleared. Starting fresh!",
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr("✅ Conversation history cleared. Starting fresh!"),
 	})
 
 	b.logger.Info().
@@ -271,7 +436,7 @@ func (b *Bot) handleClearButton(s *discordgo.Session, i *discordgo.InteractionCr
 		Msg("Conversation cleared")
 }
 
-// handleSettingsButton shows model and prompt selection menus
+// handleSettingsButton shows model, prompt and context strategy selection menus
 func (b *Bot) handleSettingsButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	ctx := context.Background()
 	threadID := i.ChannelID
@@ -337,6 +502,18 @@ func (b *Bot) handleSettingsButton(s *discordgo.Session, i *discordgo.Interactio
 		})
 	}
 
+	// Build context strategy options
+	strategy := b.conversationStrategy(cfg, guildCfg, conv)
+	strategyOptions := []discordgo.SelectMenuOption{}
+	for _, name := range conversation.StrategyNames() {
+		strategyOptions = append(strategyOptions, discordgo.SelectMenuOption{
+			Label:       name,
+			Value:       "strategy:" + name,
+			Description: strategyDescriptions[name],
+			Default:     name == strategy,
+		})
+	}
+
 	// Build response with select menus
 	components := []discordgo.MessageComponent{}
 
@@ -364,16 +541,90 @@ func (b *Bot) handleSettingsButton(s *discordgo.Session, i *discordgo.Interactio
 		})
 	}
 
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    "strategy_select",
+				Placeholder: "Change context strategy",
+				Options:     strategyOptions,
+			},
+		},
+	})
+
+	// Build posting policy options
+	posting := conv.Posting
+	if posting == "" {
+		posting = conversation.PostingAnyone
+	}
+	postingOptions := []discordgo.SelectMenuOption{}
+	for _, policy := range []string{conversation.PostingAnyone, conversation.PostingOwner, conversation.PostingAllowlist} {
+		postingOptions = append(postingOptions, discordgo.SelectMenuOption{
+			Label:       policy,
+			Value:       "posting:" + policy,
+			Description: postingDescriptions[policy],
+			Default:     policy == posting,
+		})
+	}
+
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    "posting_select",
+				Placeholder: "Change who can post",
+				Options:     postingOptions,
+			},
+		},
+	})
+
+	// Allowlisted posters are picked from the guild's members
+	posters := []discordgo.SelectMenuDefaultValue{}
+	for _, userID := range conv.Posters {
+		posters = append(posters, discordgo.SelectMenuDefaultValue{ID: userID, Type: discordgo.SelectMenuDefaultValueUser})
+	}
+	minPosters := 0
+	components = append(components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:      discordgo.UserSelectMenu,
+				CustomID:      "posters_select",
+				Placeholder:   "Choose who can post (allowlist)",
+				MinValues:     &minPosters,
+				MaxValues:     maxPosters,
+				DefaultValues: posters,
+			},
+		},
+	})
+
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content:    fmt.Sprintf("**Current Settings**\nModel: `%s`\nSystem Prompt: `%s`", conv.Model, findPromptName(guildCfg, conv.SystemPrompt)),
+			Content:    fmt.Sprintf("**Current Settings**\nModel: `%s`\nSystem Prompt: `%s`\nContext Strategy: `%s`\nWho Can Post: `%s`", conv.Model, findPromptName(guildCfg, conv.SystemPrompt), strategy, posting),
 			Components: components,
 			Flags:      discordgo.MessageFlagsEphemeral,
 		},
 	})
 }
 
+// deferAndLock acknowledges a conversation interaction with an ephemeral response to edit later, then waits
+// for any turn in progress so the change isn't overwritten or made underneath it.
+// Returns nil, after telling the user, if the conversation couldn't be locked.
+func (b *Bot) deferAndLock(ctx context.Context, s *discordgo.Session, i *discordgo.InteractionCreate) func() {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	unlock, err := b.lockConversation(ctx, i.GuildID, i.ChannelID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", i.ChannelID).Msg("Failed to lock conversation")
+		b.editInteractionError(s, i, "Another reply in this thread is still being written")
+		return nil
+	}
+	return unlock
+}
+
 // handleModelSelect handles model selection from settings menu
 func (b *Bot) handleModelSelect(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	ctx := context.Background()
@@ -386,32 +637,41 @@ func (b *Bot) handleModelSelect(s *discordgo.Session, i *discordgo.InteractionCr
 
 	modelRef := strings.TrimPrefix(data.Values[0], "model:")
 
+	// Fitting the history to a smaller model can take a while, and mustn't compact it underneath a turn
+	unlock := b.deferAndLock(ctx, s, i)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
 	// Update conversation
 	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
 	if err != nil {
-		b.respondError(s, i, "Failed to load conversation")
+		b.editInteractionError(s, i, "Failed to load conversation")
 		return
 	}
+	oldModel := conv.Model
 
 	err = b.convManager.UpdateModel(ctx, i.GuildID, threadID, modelRef)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to update model")
-		b.respondError(s, i, "Failed to update model")
+		b.editInteractionError(s, i, "Failed to update model")
 		return
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("✅ Model changed to `%s`", modelRef),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
+	content := fmt.Sprintf("✅ Model changed to `%s`", modelRef)
+	if warning := b.fitContextWindow(ctx, s, conv, modelRef, i.Member); warning != "" {
+		content += "\n" + warning
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
 	})
 
 	b.logger.Info().
 		Str("user", i.Member.User.Username).
 		Str("thread", threadID).
-		Str("old_model", conv.Model).
+		Str("old_model", oldModel).
 		Str("new_model", modelRef).
 		Msg("Model changed")
 }
@@ -428,18 +688,25 @@ func (b *Bot) handlePromptSelect(s *discordgo.Session, i *discordgo.InteractionC
 
 	promptName := strings.TrimPrefix(data.Values[0], "prompt:")
 
+	// Wait for any turn in progress so it doesn't write back the old settings
+	unlock := b.deferAndLock(ctx, s, i)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
 	// Get guild config
 	cfg := b.GetConfig()
 	guildCfg, err := cfg.GetGuild(i.GuildID)
 	if err != nil {
-		b.respondError(s, i, "Failed to get guild config")
+		b.editInteractionError(s, i, "Failed to get guild config")
 		return
 	}
 
 	// Get prompt content
 	promptContent, err := guildCfg.GetSystemPromptByName(promptName)
 	if err != nil {
-		b.respondError(s, i, fmt.Sprintf("System prompt not found: %s", promptName))
+		b.editInteractionError(s, i, fmt.Sprintf("System prompt not found: %s", promptName))
 		return
 	}
 
@@ -447,16 +714,12 @@ func (b *Bot) handlePromptSelect(s *discordgo.Session, i *discordgo.InteractionC
 	err = b.convManager.UpdateSystemPrompt(ctx, i.GuildID, threadID, promptContent)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to update system prompt")
-		b.respondError(s, i, "Failed to update system prompt")
+		b.editInteractionError(s, i, "Failed to update system prompt")
 		return
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("✅ System prompt changed to `%s`", promptName),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(fmt.Sprintf("✅ System prompt changed to `%s`", promptName)),
 	})
 
 	b.logger.Info().
@@ -466,13 +729,198 @@ func (b *Bot) handlePromptSelect(s *discordgo.Session, i *discordgo.InteractionC
 		Msg("System prompt changed")
 }
 
+// handleStrategySelect handles context strategy selection from settings menu
+func (b *Bot) handleStrategySelect(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	data := i.MessageComponentData()
+	if len(data.Values) == 0 {
+		return
+	}
+
+	strategy := strings.TrimPrefix(data.Values[0], "strategy:")
+	if !config.ValidContextStrategy(strategy) {
+		b.respondError(s, i, fmt.Sprintf("Unknown context strategy: %s", strategy))
+		return
+	}
+
+	// Wait for any turn in progress so it doesn't write back the old settings
+	unlock := b.deferAndLock(ctx, s, i)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
+	// Load conversation
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to load conversation")
+		return
+	}
+
+	// Only owner or admins can change settings
+	if conv.UserID != i.Member.User.ID && !b.rbacManager.HasPermission(i.GuildID, i.Member, "manage_prompts") {
+		b.editInteractionError(s, i, "You can only modify your own conversations")
+		return
+	}
+
+	// Update conversation
+	err = b.convManager.UpdateStrategy(ctx, i.GuildID, threadID, strategy)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to update context strategy")
+		b.editInteractionError(s, i, "Failed to update context strategy")
+		return
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(fmt.Sprintf("✅ Context strategy changed to `%s`", strategy)),
+	})
+
+	b.logger.Info().
+		Str("user", i.Member.User.Username).
+		Str("thread", threadID).
+		Str("old_strategy", conv.Strategy).
+		Str("new_strategy", strategy).
+		Msg("Context strategy changed")
+}
+
+// postingDescriptions explains each posting policy in the settings menu
+var postingDescriptions = map[string]string{
+	conversation.PostingAnyone:    "Anyone who can use models may post",
+	conversation.PostingOwner:     "Only the conversation owner may post",
+	conversation.PostingAllowlist: "The owner and the people picked below may post",
+}
+
+// maxPosters is the most users a posting allowlist can hold (Discord's select menu limit)
+const maxPosters = 25
+
+// handlePostingSelect handles posting policy selection from settings menu
+func (b *Bot) handlePostingSelect(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	data := i.MessageComponentData()
+	if len(data.Values) == 0 {
+		return
+	}
+
+	policy := strings.TrimPrefix(data.Values[0], "posting:")
+	if !conversation.ValidPostingPolicy(policy) {
+		b.respondError(s, i, fmt.Sprintf("Unknown posting policy: %s", policy))
+		return
+	}
+
+	// Wait for any turn in progress so it doesn't write back the old settings
+	unlock := b.deferAndLock(ctx, s, i)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
+	// Load conversation
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to load conversation")
+		return
+	}
+
+	// Only owner or admins can change settings
+	if conv.UserID != i.Member.User.ID && !b.rbacManager.HasPermission(i.GuildID, i.Member, "manage_prompts") {
+		b.editInteractionError(s, i, "You can only modify your own conversations")
+		return
+	}
+
+	// Update conversation
+	err = b.convManager.UpdatePosting(ctx, i.GuildID, threadID, policy, conv.Posters)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to update posting policy")
+		b.editInteractionError(s, i, "Failed to update posting policy")
+		return
+	}
+
+	content := fmt.Sprintf("✅ Posting policy changed to `%s`", policy)
+	if policy == conversation.PostingAllowlist && len(conv.Posters) == 0 {
+		content += "\nPick who can post with the user menu in ⚙️ Settings."
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(content),
+	})
+
+	b.logger.Info().
+		Str("user", i.Member.User.Username).
+		Str("thread", threadID).
+		Str("old_policy", conv.Posting).
+		Str("new_policy", policy).
+		Msg("Posting policy changed")
+}
+
+// handlePostersSelect handles the allowlist user picker from settings menu
+func (b *Bot) handlePostersSelect(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	threadID := i.ChannelID
+	posters := i.MessageComponentData().Values
+
+	// Wait for any turn in progress so it doesn't write back the old settings
+	unlock := b.deferAndLock(ctx, s, i)
+	if unlock == nil {
+		return
+	}
+	defer unlock()
+
+	// Load conversation
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to load conversation")
+		return
+	}
+
+	// Only owner or admins can change settings
+	if conv.UserID != i.Member.User.ID && !b.rbacManager.HasPermission(i.GuildID, i.Member, "manage_prompts") {
+		b.editInteractionError(s, i, "You can only modify your own conversations")
+		return
+	}
+
+	// Picking people implies the allowlist policy
+	err = b.convManager.UpdatePosting(ctx, i.GuildID, threadID, conversation.PostingAllowlist, posters)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to update posting allowlist")
+		b.editInteractionError(s, i, "Failed to update posting allowlist")
+		return
+	}
+
+	mentions := make([]string, len(posters))
+	for idx, userID := range posters {
+		mentions[idx] = fmt.Sprintf("<@%s>", userID)
+	}
+	content := fmt.Sprintf("✅ Only <@%s> can post in this conversation now", conv.UserID)
+	if len(mentions) > 0 {
+		content = fmt.Sprintf("✅ <@%s> and %s can post in this conversation", conv.UserID, strings.Join(mentions, ", "))
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         stringPtr(content),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+
+	b.logger.Info().
+		Str("user", i.Member.User.Username).
+		Str("thread", threadID).
+		Int("posters", len(posters)).
+		Msg("Posting allowlist changed")
+}
+
 // Helper functions
 
+// truncate shortens s to at most maxLen characters, ending it with "..." if anything was cut
+// Lengths are counted in runes, as Discord counts them, so multi-byte characters are never split.
 func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
+	return string(runes[:maxLen-3]) + "..."
 }
 
 func findPromptName(guildCfg *config.GuildConfig, content string) string {
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/conversation"
+)
+
+// strategyDescriptions are shown in the settings menu
+var strategyDescriptions = map[string]string{
+	conversation.StrategyTruncate:      "Keep the newest messages that fit",
+	conversation.StrategyPinFirst:      "Always keep the first message, then the newest",
+	conversation.StrategySlidingWindow: "Keep only the last few turns",
+	conversation.StrategySummary:       "Summarize history that no longer fits",
+	conversation.StrategyError:         "Stop when the conversation is full",
+}
+
+// contextFullMessage is shown when the error strategy refuses to drop history
+const contextFullMessage = "❌ This conversation no longer fits in the model's context window. Use 🗑️ Clear to start over, or pick another context strategy in ⚙️ Settings."
+
+// contextLimits describes how a model's context window is split between history and the response
+type contextLimits struct {
+	budget      int // Total tokens for context plus response (model window capped by guild policy)
+	reserve     int // Tokens always kept free for the response
+	maxResponse int // Provider cap on response tokens
+}
+
+// resolveContextLimits computes the context limits for a model in a guild
+func resolveContextLimits(cfg *config.Config, guildCfg *config.GuildConfig, modelRef string) contextLimits {
+	limits := contextLimits{
+		budget:      guildCfg.GetMaxContextTokens(cfg.Defaults),
+		maxResponse: config.DefaultResponseTokens,
+	}
+	if provider, model, err := cfg.ResolveModel(modelRef); err == nil {
+		limits.budget = guildCfg.GetContextBudget(cfg.Defaults, model)
+		limits.maxResponse = provider.GetDefaultMaxTokens()
+	}
+
+	// Keep at most a quarter of the window for the response so small models still get history
+	limits.reserve = limits.maxResponse
+	if limits.reserve > limits.budget/4 {
+		limits.reserve = limits.budget / 4
+	}
+
+	return limits
+}
+
+// maxTokens returns the response token limit left over after a context of contextTokens
+func (l contextLimits) maxTokens(contextTokens int) int {
+	remaining := l.budget - contextTokens
+	if remaining > l.maxResponse {
+		remaining = l.maxResponse
+	}
+	if remaining < l.reserve {
+		// Context is already over budget (e.g. a huge system prompt); let the provider decide
+		remaining = l.reserve
+	}
+	return remaining
+}
+
+// conversationStrategy resolves the context strategy name for a conversation
+// The conversation's own setting wins over the guild and global defaults
+func (b *Bot) conversationStrategy(cfg *config.Config, guildCfg *config.GuildConfig, conv *conversation.Conversation) string {
+	if conv.Strategy != "" {
+		return conv.Strategy
+	}
+	if strategy := guildCfg.GetContextStrategy(cfg.Defaults); strategy != "" {
+		return strategy
+	}
+	return conversation.StrategyTruncate
+}
+
+// buildContext builds the model context for a conversation turn using the conversation's strategy.
+// With the summary strategy, history that no longer fits is summarized into the conversation's
+// rolling summary and removed from storage instead of being silently dropped.
+// stored is how many of messages (from the start) are already persisted in Redis.
+// Also returns the max_tokens to request, derived from what's left of the model's window.
+func (b *Bot) buildContext(ctx context.Context, s *discordgo.Session, cfg *config.Config, guildCfg *config.GuildConfig, conv *conversation.Conversation, messages []conversation.Message, stored int, member *discordgo.Member) (*conversation.BuildResult, int, error) {
+	strategy, err := conversation.NewStrategy(b.conversationStrategy(cfg, guildCfg, conv), guildCfg.GetSlidingWindowTurns(cfg.Defaults))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	limits := resolveContextLimits(cfg, guildCfg, conv.Model)
+	builder := conversation.NewContextBuilder(limits.budget, limits.reserve).WithStrategy(strategy)
+
+	result, err := builder.BuildWithSummary(messages, conv.Summary, conv.SystemPrompt, conv.Model)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if result.Overflow == 0 || strategy.Name() != conversation.StrategySummary {
+		return result, limits.maxTokens(result.Tokens), nil
+	}
+
+	// Only persisted messages can be compacted away
+	count := result.Overflow
+	if count > stored {
+		count = stored
+	}
+
+	// The stored system prompt isn't history, so it's kept rather than compacted
+	start := 0
+	for start < count && messages[start].Role == "system" {
+		start++
+	}
+
+	transcript := renderTranscript(messages[start:count], 0)
+	if transcript == "" {
+		return result, limits.maxTokens(result.Tokens), nil
+	}
+
+	summaryModel := guildCfg.GetSummaryModel(cfg.Defaults, conv.Model)
+	summary, usage, err := b.llmRegistry.Summarize(ctx, summaryModel, conv.Summary, transcript)
+	b.recordExtraTokens(ctx, guildCfg, member, usage)
+	conv.TokenCount += usage.TotalTokens
+	b.convManager.IncrementTokenCount(ctx, conv.GuildID, conv.ThreadID, usage.TotalTokens)
+	if err != nil {
+		// Fall back to plain truncation for this turn
+		b.logger.Warn().Err(err).Str("thread", conv.ThreadID).Msg("Failed to summarize context, dropping old messages")
+		return result, limits.maxTokens(result.Tokens), nil
+	}
+
+	if err := b.convManager.Compact(ctx, conv.GuildID, conv.ThreadID, count-start, summary); err != nil {
+		b.logger.Error().Err(err).Str("thread", conv.ThreadID).Msg("Failed to store compacted context")
+		return result, limits.maxTokens(result.Tokens), nil
+	}
+	conv.Summary = summary
+
+	sendNotice(s, conv.ThreadID, fmt.Sprintf("🗜️ Summarized %d earlier message(s) to stay within the context window.", count-start))
+
+	b.logger.Info().
+		Str("thread", conv.ThreadID).
+		Str("model", summaryModel).
+		Int("compacted", count-start).
+		Int("tokens", usage.TotalTokens).
+		Msg("Context compacted")
+
+	// Rebuild with the summary in place of the compacted messages
+	result, err = builder.BuildWithSummary(messages[count:], conv.Summary, conv.SystemPrompt, conv.Model)
+	if err != nil {
+		return nil, 0, err
+	}
+	return result, limits.maxTokens(result.Tokens), nil
+}
+
+// fitContextWindow fits a conversation switched to a model with a smaller window to its new budget
+// Summarized conversations are compacted right away; otherwise the returned warning explains what happens to
+// the history. Returns an empty string when the new window is at least as large or the history still fits.
+// The caller must hold the conversation's lock.
+func (b *Bot) fitContextWindow(ctx context.Context, s *discordgo.Session, conv *conversation.Conversation, newModel string, member *discordgo.Member) string {
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(conv.GuildID)
+	if err != nil {
+		return ""
+	}
+
+	oldLimits := resolveContextLimits(cfg, guildCfg, conv.Model)
+	newLimits := resolveContextLimits(cfg, guildCfg, newModel)
+	if newLimits.budget >= oldLimits.budget {
+		return ""
+	}
+
+	messages, err := b.convManager.GetMessages(ctx, conv.GuildID, conv.ThreadID)
+	if err != nil {
+		return ""
+	}
+
+	// Measure the history as the new model will see it
+	builder := conversation.NewContextBuilder(newLimits.budget, newLimits.reserve)
+	used := 0
+	for _, content := range []string{conv.SystemPrompt, conv.Summary} {
+		if content == "" {
+			continue
+		}
+		tokens, _ := builder.CountTokens(content, newModel)
+		used += tokens + 4
+	}
+	for _, msg := range messages {
+		used += msg.Tokens
+	}
+
+	available := newLimits.budget - newLimits.reserve
+	if used <= available {
+		return ""
+	}
+
+	warning := fmt.Sprintf("⚠️ `%s` has a smaller context window (%d tokens for history, this conversation uses about %d). ", newModel, available, used)
+	switch b.conversationStrategy(cfg, guildCfg, conv) {
+	case conversation.StrategySummary:
+		// Compact now rather than leave the stored history over budget until the next message
+		conv.Model = newModel
+		summary := conv.Summary
+		if _, _, err := b.buildContext(ctx, s, cfg, guildCfg, conv, messages, len(messages), member); err == nil && conv.Summary != summary {
+			return ""
+		}
+		warning += "Older messages will be summarized on the next message."
+	case conversation.StrategyError:
+		warning += "The conversation no longer fits: use 🗑️ Clear or pick another context strategy before continuing."
+	default:
+		warning += "Older messages will be left out of the model's context."
+	}
+	return warning
+}
@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/moderation"
+)
+
+// continuePrompt asks the model to pick up a reply that was cut off by the token limit
+// It is only sent to the model, never stored in the history.
+const continuePrompt = "Your previous reply was cut off. Continue it exactly where it stopped, without repeating anything or adding an introduction."
+
+// handleContinueButton asks the model to finish a reply that was cut off by the token limit
+// The continuation is appended to the same stored reply and edited into its messages.
+func (b *Bot) handleContinueButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Acknowledge the interaction
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	// Wait for any turn in progress so the reply isn't continued twice
+	unlock, err := b.lockConversation(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to lock conversation")
+		sendNotice(s, threadID, "❌ Another reply in this thread is still being written")
+		return
+	}
+	defer unlock()
+
+	// Load conversation
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load conversation")
+		sendNotice(s, threadID, "❌ Failed to load conversation")
+		return
+	}
+
+	// Get guild config
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(i.GuildID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to get guild config")
+		return
+	}
+
+	// Get member with roles
+	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to get member info")
+		return
+	}
+
+	// Check permissions
+	if !b.rbacManager.HasPermission(i.GuildID, member, "use_models") {
+		sendNotice(s, threadID, "❌ You don't have permission to use models")
+		return
+	}
+
+	// Check rate limits
+	rateResult, err := b.rateLimiter.CheckRateLimit(ctx, i.GuildID, member.User.ID, b.getRateLimitForMember(guildCfg, member))
+	if err != nil || !rateResult.Allowed {
+		sendNotice(s, threadID, fmt.Sprintf("❌ Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
+		return
+	}
+
+	// Load message history
+	messages, err := b.convManager.GetMessages(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load messages")
+		sendNotice(s, threadID, "❌ Failed to load message history")
+		return
+	}
+
+	// Only the latest reply can be continued, and only while it is still cut off
+	idx := conversation.FindMessage(messages, i.Message.ID)
+	if idx < 0 || idx != len(messages)-1 || !messages[idx].Truncated() {
+		sendNotice(s, threadID, "❌ Only the latest reply can be continued")
+		return
+	}
+	reply := messages[idx]
+
+	// Check token limits
+	tokenResult, err := b.rateLimiter.CheckTokenLimit(ctx, i.GuildID, member.User.ID, b.getTokenLimitForMember(guildCfg, member), conversation.TotalTokens(messages)+1000)
+	if err != nil || !tokenResult.Allowed {
+		sendNotice(s, threadID, fmt.Sprintf("❌ Token limit exceeded. Resets in %d seconds.", tokenResult.SecondsToReset))
+		return
+	}
+
+	// Build context with the cut-off reply last, then ask for the rest of it
+	// The reply itself is kept out of compaction since the model has to see all of it to carry on.
+	built, maxTokens, err := b.buildContext(ctx, s, cfg, guildCfg, conv, messages, len(messages)-1, member)
+	if errors.Is(err, conversation.ErrContextFull) {
+		sendNotice(s, threadID, contextFullMessage)
+		return
+	} else if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to build context")
+		sendNotice(s, threadID, "❌ Failed to build context")
+		return
+	}
+	contextMessages := append(built.Messages, conversation.Message{Role: "user", Content: continuePrompt})
+
+	// Show typing
+	s.ChannelTyping(threadID)
+
+	exchange, err := b.chat(ctx, moderation.Request{
+		Subject:     moderation.Subject{GuildID: i.GuildID, ChannelID: threadID, UserID: member.User.ID},
+		ModelRef:    conv.Model,
+		Messages:    toLLMMessages(cfg, conv.Model, contextMessages),
+		MaxTokens:   maxTokens,
+		Temperature: replyTemperature,
+		SkipInput:   true, // The continuation prompt is ours
+	})
+	if err != nil {
+		if msg, ok := moderationBlockedMessage(err); ok {
+			sendNotice(s, threadID, msg)
+			return
+		}
+		b.logger.Error().Err(err).Msg("LLM request failed")
+		sendNotice(s, threadID, fmt.Sprintf("❌ Failed to continue: %v", err))
+		return
+	}
+	response := exchange.Response
+
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		sendNotice(s, threadID, "❌ No response from model")
+		return
+	}
+
+	// Append the continuation to the stored reply
+	continuation := response.Choices[0].Message.Content
+	reply.AppendContinuation(replyMessage(conv.Model, replyTemperature, exchange, continuation))
+
+	// Re-post the whole reply over its messages, adding more if it no longer fits
+	layout := b.replyLayout(i.GuildID, reply, messageFooter(i.Message))
+	ids, staleParts, err := b.sendReply(s, i.GuildID, threadID, reply.DiscordIDs(), layout, replyComponents(reply, i.Message.ID), nil)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to post continuation")
+		sendNotice(s, threadID, "❌ Failed to post the rest of the reply")
+		return
+	}
+	reply.SetDiscordIDs(ids)
+
+	// Compaction may have trimmed the history before the reply, so find it again and update only it
+	if err := b.saveContinuation(ctx, i.GuildID, threadID, i.Message.ID, reply); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to save continuation")
+	}
+	for _, id := range staleParts {
+		s.ChannelMessageDelete(threadID, id)
+	}
+
+	// Update token count
+	b.convManager.IncrementTokenCount(ctx, i.GuildID, threadID, response.Usage.TotalTokens)
+
+	if notice := moderationNotice(exchange.Output); notice != "" {
+		sendNotice(s, threadID, notice)
+	}
+
+	b.logger.Info().
+		Str("user", member.User.Username).
+		Str("model", conv.Model).
+		Str("thread", threadID).
+		Str("finish_reason", reply.FinishReason).
+		Int("tokens", response.Usage.TotalTokens).
+		Msg("Response continued")
+}
+
+// saveContinuation stores a continued reply over the history entry it was posted as
+func (b *Bot) saveContinuation(ctx context.Context, guildID, threadID, messageID string, reply conversation.Message) error {
+	messages, err := b.convManager.GetMessages(ctx, guildID, threadID)
+	if err != nil {
+		return err
+	}
+	idx := conversation.FindMessage(messages, messageID)
+	if idx < 0 {
+		return fmt.Errorf("reply %s is no longer in the history", messageID)
+	}
+	return b.convManager.SetMessage(ctx, guildID, threadID, idx, reply)
+}
@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// conversationsPageSize is the number of conversations shown per /conversations page
+const conversationsPageSize = 10
+
+// handleConversations handles the /conversations command - lists the user's conversation threads
+func (b *Bot) handleConversations(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	all := getBoolOption(i.ApplicationCommandData().Options, "all")
+	if all && !b.canListAllConversations(s, i) {
+		b.respondError(s, i, "You don't have permission to list everyone's conversations")
+		return
+	}
+
+	content, components, err := b.conversationsPage(i, all, 0)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to list conversations")
+		b.respondError(s, i, "Failed to load conversations")
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Components:      components,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// handleConversationsPage handles the ◀/▶ buttons on a /conversations listing
+// Custom IDs have the form "conversations:<me|all>:<page>"
+func (b *Bot) handleConversationsPage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 3 {
+		b.respondError(s, i, "Invalid page")
+		return
+	}
+	page, err := strconv.Atoi(parts[2])
+	if err != nil || page < 0 {
+		b.respondError(s, i, "Invalid page")
+		return
+	}
+
+	all := parts[1] == "all"
+	if all && !b.canListAllConversations(s, i) {
+		b.respondError(s, i, "You don't have permission to list everyone's conversations")
+		return
+	}
+
+	content, components, err := b.conversationsPage(i, all, page)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to list conversations")
+		b.respondError(s, i, "Failed to load conversations")
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:         content,
+			Components:      components,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// canListAllConversations reports whether the member may list everyone's conversations
+func (b *Bot) canListAllConversations(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		return false
+	}
+	return b.rbacManager.HasPermission(i.GuildID, member, "manage_prompts")
+}
+
+// conversationsPage renders one page of the /conversations listing
+func (b *Bot) conversationsPage(i *discordgo.InteractionCreate, all bool, page int) (string, []discordgo.MessageComponent, error) {
+	ctx := context.Background()
+
+	userID := i.Member.User.ID
+	if all {
+		userID = ""
+	}
+
+	conversations, total, err := b.convManager.List(ctx, i.GuildID, userID, page*conversationsPageSize, conversationsPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pages := (total + conversationsPageSize - 1) / conversationsPageSize
+	if pages == 0 {
+		pages = 1
+	}
+
+	var sb strings.Builder
+	if all {
+		sb.WriteString("**Server Conversations**\n\n")
+	} else {
+		sb.WriteString("**Your Conversations**\n\n")
+	}
+
+	if len(conversations) == 0 {
+		if page == 0 {
+			sb.WriteString("No conversations yet. Use `/ask` to start one.")
+		} else {
+			sb.WriteString("No more conversations.")
+		}
+	}
+
+	for _, conv := range conversations {
+		title := conv.Title
+		if title == "" {
+			title = "Untitled conversation"
+		}
+		link := fmt.Sprintf("https://discord.com/channels/%s/%s", conv.GuildID, conv.ThreadID)
+
+		sb.WriteString(fmt.Sprintf("• [%s](%s) · `%s` · active <t:%d:R>", escapeLinkText(title), link, conv.Model, conv.UpdatedAt.Unix()))
+		if all {
+			sb.WriteString(fmt.Sprintf(" · <@%s>", conv.UserID))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\nPage %d/%d · %d conversation(s)", page+1, pages, total))
+
+	scope := "me"
+	if all {
+		scope = "all"
+	}
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "◀ Previous",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("conversations:%s:%d", scope, page-1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next ▶",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("conversations:%s:%d", scope, page+1),
+					Disabled: page+1 >= pages,
+				},
+			},
+		},
+	}
+
+	return sb.String(), components, nil
+}
+
+// escapeLinkText keeps a title from breaking out of Markdown link text
+func escapeLinkText(text string) string {
+	return strings.NewReplacer("[", "\\[", "]", "\\]").Replace(text)
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+)
+
+// handleMessageDelete removes a deleted thread message from the conversation history
+func (b *Bot) handleMessageDelete(s *discordgo.Session, m *discordgo.MessageDelete) {
+	b.removeDeletedMessages(m.GuildID, m.ChannelID, []string{m.ID})
+}
+
+// handleMessageDeleteBulk removes bulk-deleted thread messages from the conversation history
+func (b *Bot) handleMessageDeleteBulk(s *discordgo.Session, m *discordgo.MessageDeleteBulk) {
+	b.removeDeletedMessages(m.GuildID, m.ChannelID, m.Messages)
+}
+
+// removeDeletedMessages drops deleted Discord messages (and the replies to deleted prompts)
+// from a conversation so they stop influencing the model
+func (b *Bot) removeDeletedMessages(guildID, threadID string, messageIDs []string) {
+	if guildID == "" || len(messageIDs) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+
+	// Only tracked conversation threads have history to sync
+	if _, err := b.convManager.Get(ctx, guildID, threadID); err != nil {
+		return
+	}
+
+	// Wait for any turn in progress so its reply isn't lost when the history is rewritten
+	unlock, err := b.lockConversation(ctx, guildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to lock conversation")
+		return
+	}
+	defer unlock()
+
+	messages, err := b.convManager.GetMessages(ctx, guildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to load message history")
+		return
+	}
+
+	kept, removed := conversation.RemoveMessages(messages, messageIDs)
+	if removed == 0 {
+		return
+	}
+
+	if err := b.convManager.ReplaceMessages(ctx, guildID, threadID, kept); err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to remove deleted messages")
+		return
+	}
+
+	b.logger.Info().
+		Str("thread", threadID).
+		Int("deleted", len(messageIDs)).
+		Int("removed", removed).
+		Msg("Deleted messages removed from history")
+}
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/moderation"
+)
+
+// handleMessageUpdate re-runs a conversation turn when a user edits their message in a thread
+func (b *Bot) handleMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	// Partial updates (embeds unfurling, pins) carry no author or unchanged content
+	if m.Author == nil || m.Author.Bot || m.GuildID == "" {
+		return
+	}
+	if m.BeforeUpdate != nil && m.BeforeUpdate.Content == m.Content {
+		return
+	}
+
+	ctx := context.Background()
+
+	// Only tracked conversation threads have history to re-run
+	if _, err := b.convManager.Get(ctx, m.GuildID, m.ChannelID); err != nil {
+		return
+	}
+
+	// Wait for any turn in progress so the history isn't rewritten underneath it
+	unlock, err := b.lockConversation(ctx, m.GuildID, m.ChannelID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", m.ChannelID).Msg("Failed to lock conversation")
+		return
+	}
+	defer unlock()
+
+	// Load conversation metadata now that no turn can change it
+	conv, err := b.convManager.Get(ctx, m.GuildID, m.ChannelID)
+	if err != nil {
+		return
+	}
+
+	// Find the edited message in the history
+	messages, err := b.convManager.GetMessages(ctx, m.GuildID, m.ChannelID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load message history")
+		return
+	}
+
+	idx := conversation.FindMessage(messages, m.ID)
+	if idx < 0 || messages[idx].Role != "user" || messages[idx].Content == m.Content {
+		return
+	}
+
+	// Get guild config
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(m.GuildID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("guild", m.GuildID).Msg("Failed to get guild config")
+		return
+	}
+
+	// Get member with roles
+	member, err := s.GuildMember(m.GuildID, m.Author.ID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("user", m.Author.ID).Msg("Failed to get member info")
+		return
+	}
+
+	// Check permissions
+	if !b.rbacManager.HasPermission(m.GuildID, member, "use_models") {
+		sendNotice(s, m.ChannelID, "❌ You don't have permission to use models")
+		return
+	}
+
+	// Check rate limits
+	rateLimitCfg := b.getRateLimitForMember(guildCfg, member)
+	rateResult, err := b.rateLimiter.CheckRateLimit(ctx, m.GuildID, m.Author.ID, rateLimitCfg)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Rate limit check failed")
+		sendNotice(s, m.ChannelID, "❌ Failed to check rate limits")
+		return
+	}
+	if !rateResult.Allowed {
+		sendNotice(s, m.ChannelID, fmt.Sprintf("❌ Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
+		return
+	}
+
+	// Count tokens in the edited message
+	tokenCounter := conversation.NewTokenCounter()
+	userTokens, err := tokenCounter.Count(m.Content, conv.Model)
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to count tokens, using estimate")
+		userTokens = len(m.Content) / 4
+	}
+	userTokens += 4 // Message overhead
+
+	// Check token limits
+	tokenLimitCfg := b.getTokenLimitForMember(guildCfg, member)
+	tokenResult, err := b.rateLimiter.CheckTokenLimit(ctx, m.GuildID, m.Author.ID, tokenLimitCfg, userTokens+1000)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Token limit check failed")
+		sendNotice(s, m.ChannelID, "❌ Failed to check token limits")
+		return
+	}
+	if !tokenResult.Allowed {
+		sendNotice(s, m.ChannelID, fmt.Sprintf("❌ Token limit exceeded. You have %d tokens remaining. Resets in %d seconds.", tokenResult.TokensRemaining, tokenResult.SecondsToReset))
+		return
+	}
+
+	// Moderate the edited message before it replaces the original
+	subject := moderation.Subject{GuildID: m.GuildID, ChannelID: m.ChannelID, UserID: m.Author.ID}
+	inputResult, err := b.moderation.Check(ctx, subject, moderation.StageInput, m.Content)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Moderation check failed")
+		sendNotice(s, m.ChannelID, "❌ Failed to run moderation checks")
+		return
+	}
+	if inputResult.Blocked() {
+		sendNotice(s, m.ChannelID, "🛡️ Your edited message was blocked by moderation.")
+		return
+	}
+
+	// The reply to the original message's turn is re-posted over its messages
+	var replyIDs []string
+	if replyIdx := conversation.ReplyIndex(messages, idx); replyIdx >= 0 {
+		replyIDs = messages[replyIdx].DiscordIDs()
+	}
+	removed := len(messages) - idx - 1
+
+	// Truncate the history at the edited message; it's only stored once the new reply is posted
+	editedMsg := messages[idx]
+	editedMsg.Content = inputResult.Content
+	editedMsg.Tokens = userTokens
+	history := append(messages[:idx:idx], editedMsg)
+
+	// Show typing indicator
+	s.ChannelTyping(m.ChannelID)
+
+	// Build context within token limits using the conversation's context strategy
+	built, maxTokens, err := b.buildContext(ctx, s, cfg, guildCfg, conv, history, idx, member)
+	if errors.Is(err, conversation.ErrContextFull) {
+		sendNotice(s, m.ChannelID, contextFullMessage)
+		return
+	} else if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to build context")
+		sendNotice(s, m.ChannelID, "❌ Failed to build conversation context")
+		return
+	}
+
+	// Convert to LLM messages
+	llmMessages := toLLMMessages(cfg, conv.Model, built.Messages)
+
+	exchange, err := b.chat(ctx, moderation.Request{
+		Subject:     subject,
+		ModelRef:    conv.Model,
+		Messages:    llmMessages,
+		MaxTokens:   maxTokens,
+		Temperature: replyTemperature,
+		SkipInput:   true, // Edit was moderated above
+	})
+	if err != nil {
+		if msg, ok := moderationBlockedMessage(err); ok {
+			sendNotice(s, m.ChannelID, msg)
+			return
+		}
+		b.logger.Error().Err(err).Msg("LLM request failed")
+		sendNotice(s, m.ChannelID, fmt.Sprintf("❌ Failed to get response from %s: %v", conv.Model, err))
+		return
+	}
+	response := exchange.Response
+
+	if len(response.Choices) == 0 {
+		sendNotice(s, m.ChannelID, "❌ No response from model")
+		return
+	}
+
+	assistantContent := response.Choices[0].Message.Content
+
+	// Edit the old reply, posting any messages that are missing
+	reply := replyMessage(conv.Model, replyTemperature, exchange, assistantContent)
+	reference := &discordgo.MessageReference{MessageID: m.ID, ChannelID: m.ChannelID, GuildID: m.GuildID}
+	ids, staleParts, err := b.sendReply(s, m.GuildID, m.ChannelID, replyIDs, b.replyLayout(m.GuildID, reply, nil), replyButtons(reply), reference)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to send message")
+		return
+	}
+
+	// Save the truncated history with the new reply
+	reply.SetDiscordIDs(ids)
+	if err := b.saveEdit(ctx, m.GuildID, m.ChannelID, editedMsg, reply); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to update conversation history")
+		sendNotice(s, m.ChannelID, "❌ Failed to update conversation history")
+		return
+	}
+
+	// The new reply replaces all messages of the old one
+	for _, id := range staleParts {
+		s.ChannelMessageDelete(m.ChannelID, id)
+	}
+
+	// Update conversation token count
+	b.convManager.IncrementTokenCount(ctx, m.GuildID, m.ChannelID, response.Usage.TotalTokens)
+
+	if removed > 1 {
+		sendNotice(s, m.ChannelID, fmt.Sprintf("✏️ Message edited: %d later message(s) were removed from the conversation history.", removed-1))
+	}
+	if notice := moderationNotice(inputResult, exchange.Output); notice != "" {
+		sendNotice(s, m.ChannelID, notice)
+	}
+
+	b.logger.Info().
+		Str("user", m.Author.Username).
+		Str("model", conv.Model).
+		Str("thread", m.ChannelID).
+		Str("message", m.ID).
+		Int("removed", removed).
+		Int("tokens", response.Usage.TotalTokens).
+		Msg("Edited message re-run")
+}
+
+// saveEdit truncates the stored history at an edited message and stores its new reply after it
+// The history is read again since compaction may have trimmed messages before the edit.
+func (b *Bot) saveEdit(ctx context.Context, guildID, threadID string, edited, reply conversation.Message) error {
+	messages, err := b.convManager.GetMessages(ctx, guildID, threadID)
+	if err != nil {
+		return err
+	}
+	idx := conversation.FindMessage(messages, edited.MessageID)
+	if idx < 0 {
+		return fmt.Errorf("edited message %s is no longer in the history", edited.MessageID)
+	}
+	return b.convManager.ReplaceMessages(ctx, guildID, threadID, append(messages[:idx:idx], edited, reply))
+}
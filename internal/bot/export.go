@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+)
+
+// handleExport handles the /export command - uploads the conversation as a file
+func (b *Bot) handleExport(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	format := getStringOption(i.ApplicationCommandData().Options, "format")
+	if format == "" {
+		format = conversation.ExportMarkdown
+	}
+
+	// Load conversation
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.respondError(s, i, "Use this command inside a conversation thread")
+		return
+	}
+
+	// Get member
+	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		b.respondError(s, i, "Failed to get member info")
+		return
+	}
+
+	// Only owner or admins can export
+	if conv.UserID != member.User.ID && !b.rbacManager.HasPermission(i.GuildID, member, "manage_prompts") {
+		b.respondError(s, i, "You can only export your own conversations")
+		return
+	}
+
+	messages, err := b.convManager.GetMessages(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load messages")
+		b.respondError(s, i, "Failed to load message history")
+		return
+	}
+
+	transcript := conversation.NewTranscript(*conv, messages, time.Now())
+	data, ext, err := conversation.Export(transcript, format)
+	if err != nil {
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("📄 Exported %d message(s) as %s", len(transcript.Messages), strings.ToUpper(ext)),
+			Files: []*discordgo.File{
+				{
+					Name:        exportFilename(conv.Title, ext),
+					ContentType: exportContentTypes[ext],
+					Reader:      bytes.NewReader(data),
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	b.logger.Info().
+		Str("user", member.User.Username).
+		Str("thread", threadID).
+		Str("format", format).
+		Int("messages", len(transcript.Messages)).
+		Msg("Conversation exported")
+}
+
+// exportContentTypes maps export file extensions to MIME types
+var exportContentTypes = map[string]string{
+	"md":   "text/markdown; charset=utf-8",
+	"json": "application/json",
+	"html": "text/html; charset=utf-8",
+}
+
+// exportFilename turns a conversation title into a safe file name
+func exportFilename(title, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, title)
+
+	// Collapse separators
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	name = strings.Trim(name, "-")
+
+	if runes := []rune(name); len(runes) > 50 {
+		name = strings.TrimRight(string(runes[:50]), "-")
+	}
+	if name == "" {
+		name = "conversation"
+	}
+
+	return name + "." + ext
+}
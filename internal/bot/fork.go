@@ -0,0 +1,187 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+)
+
+// forkCommandName is the message context-menu command that forks a conversation
+const forkCommandName = "Fork from here"
+
+// handleFork handles the "Fork from here" context-menu command
+// Copies the history up to the selected message into a new thread and conversation
+func (b *Bot) handleFork(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Defer initial response to avoid timeout
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	ctx := context.Background()
+	threadID := i.ChannelID
+	messageID := i.ApplicationCommandData().TargetID
+
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(i.GuildID)
+	if err != nil {
+		b.editInteractionError(s, i, "This bot is not configured for this server")
+		return
+	}
+
+	// Load the parent conversation
+	parent, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.editInteractionError(s, i, "Forks can only be made from messages in a conversation thread")
+		return
+	}
+
+	// Get member with roles
+	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to get member information")
+		return
+	}
+
+	// Check permissions
+	if !b.rbacManager.HasPermission(i.GuildID, member, "use_models") {
+		b.editInteractionError(s, i, "You don't have permission to use models")
+		return
+	}
+	if !b.rbacManager.CanUseModel(i.GuildID, member, parent.Model) {
+		b.editInteractionError(s, i, fmt.Sprintf("You don't have access to model: %s", parent.Model))
+		return
+	}
+
+	// Copy history up to the selected message
+	messages, err := b.convManager.GetMessages(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load messages")
+		b.editInteractionError(s, i, "Failed to load message history")
+		return
+	}
+
+	history, err := conversation.ForkHistory(messages, messageID)
+	if errors.Is(err, conversation.ErrMessageNotInHistory) {
+		b.editInteractionError(s, i, "That message isn't part of this conversation's history")
+		return
+	} else if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to copy history")
+		b.editInteractionError(s, i, "Failed to copy conversation history")
+		return
+	}
+
+	// Create the fork's thread next to the parent thread
+	title := truncate("🍴 "+parent.Title, 100)
+	thread, err := s.ThreadStartComplex(parent.ChannelID, &discordgo.ThreadStart{
+		Name:                title,
+		AutoArchiveDuration: guildCfg.GetAutoArchiveDuration(cfg.Defaults),
+		Type:                discordgo.ChannelTypeGuildPublicThread,
+	})
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to create thread")
+		b.editInteractionError(s, i, "Failed to create fork thread")
+		return
+	}
+
+	child := conversation.Conversation{
+		ThreadID:     thread.ID,
+		GuildID:      i.GuildID,
+		ChannelID:    parent.ChannelID,
+		UserID:       member.User.ID,
+		Model:        parent.Model,
+		SystemPrompt: parent.SystemPrompt,
+		Title:        title,
+		Retitled:     true, // Keep the fork recognizable next to its parent
+		Summary:      parent.Summary,
+		Strategy:     parent.Strategy,
+		ParentID:     parent.ThreadID,
+		ForkedFrom:   messageID,
+		TokenCount:   conversation.TotalTokens(history),
+	}
+
+	if err := b.convManager.Fork(ctx, child, history); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to save fork")
+		b.editInteractionError(s, i, "Failed to save forked conversation")
+		s.ChannelDelete(thread.ID)
+		return
+	}
+
+	// Introduce the fork in its thread
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", i.GuildID, threadID, messageID)
+	s.ChannelMessageSendComplex(thread.ID, &discordgo.MessageSend{
+		Content: withFooter(fmt.Sprintf("🍴 Forked from <#%s> at %s by <@%s> with %d message(s) of history. Continue the conversation here.", threadID, link, member.User.ID, len(history)), conversation.Footer{Model: child.Model}),
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{Label: "⚙️ Settings", Style: discordgo.SecondaryButton, CustomID: "settings"},
+				},
+			},
+		},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(fmt.Sprintf("✅ Forked conversation: <#%s>", thread.ID)),
+	})
+
+	b.logger.Info().
+		Str("user", member.User.Username).
+		Str("parent", threadID).
+		Str("thread", thread.ID).
+		Str("message", messageID).
+		Int("messages", len(history)).
+		Msg("Conversation forked")
+}
+
+// handleForks handles the /forks command - lists a conversation's parent and forks
+func (b *Bot) handleForks(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.respondError(s, i, "Use this command inside a conversation thread")
+		return
+	}
+
+	forks, err := b.convManager.GetForks(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to get forks")
+		b.respondError(s, i, "Failed to load forks")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("**Conversation Forks**\n\n")
+
+	if conv.ParentID != "" {
+		sb.WriteString(fmt.Sprintf("Forked from <#%s>", conv.ParentID))
+		if conv.ForkedFrom != "" {
+			sb.WriteString(fmt.Sprintf(" at https://discord.com/channels/%s/%s/%s", i.GuildID, conv.ParentID, conv.ForkedFrom))
+		}
+		sb.WriteString("\n\n")
+	}
+
+	if len(forks) == 0 {
+		sb.WriteString("No forks yet. Right-click a message → Apps → **Fork from here** to create one.")
+	} else {
+		for _, fork := range forks {
+			sb.WriteString(fmt.Sprintf("• <#%s>\n", fork))
+		}
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: sb.String(),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -29,6 +29,18 @@ func (b *Bot) handleCommand(s *discordgo.Session, i *discordgo.InteractionCreate
 		b.handleUsage(s, i)
 	case "reload":
 		b.handleReload(s, i)
+	case "forks":
+		b.handleForks(s, i)
+	case "conversations":
+		b.handleConversations(s, i)
+	case "search":
+		b.handleSearch(s, i)
+	case "export":
+		b.handleExport(s, i)
+	case "import":
+		b.handleImport(s, i)
+	case forkCommandName:
+		b.handleFork(s, i)
 	default:
 		b.respondError(s, i, "Unknown command")
 	}
@@ -41,9 +53,12 @@ func (b *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		return
 	}
 
-	// Check if message is in a thread
-	channel, err := s.Channel(m.ChannelID)
-	if err != nil || !channel.IsThread() {
+	// Conversations only live in threads the bot started, so skip others before locking anything
+	channel, err := s.State.Channel(m.ChannelID)
+	if err != nil {
+		channel, err = s.Channel(m.ChannelID)
+	}
+	if err != nil || !channel.IsThread() || channel.OwnerID != s.State.User.ID {
 		return
 	}
 
@@ -56,11 +71,20 @@ func (b *Bot) respondMessage(s *discordgo.Session, i *discordgo.InteractionCreat
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
 		Data: &discordgo.InteractionResponseData{
-			Content: content,
+			Content:         content,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
 		},
 	})
 }
 
+// sendNotice posts a bot notice in a channel without pinging anyone it mentions
+func sendNotice(s *discordgo.Session, channelID, content string) {
+	s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
 func (b *Bot) respondError(s *discordgo.Session, i *discordgo.InteractionCreate, errMsg string) {
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/moderation"
+)
+
+// maxImportBytes limits the size of imported transcript files
+const maxImportBytes = 1 << 20 // 1 MiB
+
+// handleImport handles the /import command - loads a transcript into a new conversation thread
+func (b *Bot) handleImport(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Defer initial response to avoid timeout
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	ctx := context.Background()
+
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(i.GuildID)
+	if err != nil {
+		b.editInteractionError(s, i, "This bot is not configured for this server")
+		return
+	}
+
+	// Get member with roles
+	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to get member information")
+		return
+	}
+
+	// Check permissions
+	if !b.rbacManager.HasPermission(i.GuildID, member, "use_models") {
+		b.editInteractionError(s, i, "You don't have permission to use models")
+		return
+	}
+
+	// Check rate limits
+	rateLimitCfg := b.getRateLimitForMember(guildCfg, member)
+	rateResult, err := b.rateLimiter.CheckRateLimit(ctx, i.GuildID, member.User.ID, rateLimitCfg)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Rate limit check failed")
+		b.editInteractionError(s, i, "Failed to check rate limits")
+		return
+	}
+	if !rateResult.Allowed {
+		b.editInteractionError(s, i, fmt.Sprintf("Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
+		return
+	}
+
+	// Read the transcript from the attachment or the pasted text
+	data := i.ApplicationCommandData()
+	raw, err := readTranscript(ctx, data)
+	if err != nil {
+		b.editInteractionError(s, i, fmt.Sprintf("Failed to read transcript: %v", err))
+		return
+	}
+
+	transcript, err := conversation.ParseTranscript(raw)
+	if err != nil {
+		b.editInteractionError(s, i, fmt.Sprintf("Invalid transcript: %v", err))
+		return
+	}
+
+	// Model: explicit option, then the transcript's model if the guild allows it, then the guild default
+	modelRef := getStringOption(data.Options, "model")
+	if modelRef == "" {
+		modelRef = transcript.Conversation.Model
+		if !contains(guildCfg.EnabledModels, modelRef) {
+			modelRef = guildCfg.DefaultModel
+		}
+	}
+	if !contains(guildCfg.EnabledModels, modelRef) {
+		b.editInteractionError(s, i, fmt.Sprintf("Model is not enabled on this server: %s", modelRef))
+		return
+	}
+	if !b.rbacManager.CanUseModel(i.GuildID, member, modelRef) {
+		b.editInteractionError(s, i, fmt.Sprintf("You don't have access to model: %s", modelRef))
+		return
+	}
+
+	// System prompt: named guild prompt, or the transcript's own if it is allowed
+	systemPrompt, notice, err := b.importSystemPrompt(guildCfg, member, getStringOption(data.Options, "system_prompt"), transcript.Conversation.SystemPrompt)
+	if err != nil {
+		b.editInteractionError(s, i, fmt.Sprintf("Invalid system prompt: %v", err))
+		return
+	}
+
+	// Moderate everything the transcript puts in front of the model like any other prompt,
+	// since its replies and summary are as much the importer's text as its prompts
+	subject := moderation.Subject{GuildID: i.GuildID, ChannelID: i.ChannelID, UserID: member.User.ID}
+	moderate := func(content, what string) (string, bool) {
+		result, err := b.moderation.Check(ctx, subject, moderation.StageInput, content)
+		if err != nil {
+			b.logger.Error().Err(err).Msg("Moderation check failed")
+			b.editInteractionError(s, i, "Failed to run moderation checks")
+			return "", false
+		}
+		if result.Blocked() {
+			b.editInteractionError(s, i, fmt.Sprintf("%s of the transcript was blocked by moderation", what))
+			return "", false
+		}
+		return result.Content, true
+	}
+	for idx, msg := range transcript.Messages {
+		content, ok := moderate(msg.Content, fmt.Sprintf("Message %d", idx+1))
+		if !ok {
+			return
+		}
+		transcript.Messages[idx].Content = content
+	}
+	if transcript.Conversation.Summary != "" {
+		summary, ok := moderate(transcript.Conversation.Summary, "The summary")
+		if !ok {
+			return
+		}
+		transcript.Conversation.Summary = summary
+	}
+	if systemPrompt != "" && systemPrompt == transcript.Conversation.SystemPrompt {
+		prompt, ok := moderate(systemPrompt, "The system prompt")
+		if !ok {
+			return
+		}
+		systemPrompt = prompt
+	}
+
+	// Re-count tokens for the target model
+	tokenCounter := conversation.NewTokenCounter()
+	systemTokens, _ := tokenCounter.Count(systemPrompt, modelRef)
+	history := []conversation.Message{
+		{Role: "system", Content: systemPrompt, Tokens: systemTokens + 4},
+	}
+	for _, msg := range transcript.Messages {
+		count, err := tokenCounter.Count(msg.Content, modelRef)
+		if err != nil {
+			count = len(msg.Content) / 4
+		}
+		msg.Tokens = count + 4 // Message overhead
+		history = append(history, msg)
+	}
+
+	// Threads can't be nested, so import next to the current thread
+	parentChannelID := i.ChannelID
+	if channel, err := s.Channel(i.ChannelID); err == nil && channel.IsThread() {
+		parentChannelID = channel.ParentID
+	}
+
+	title := transcript.Conversation.Title
+	if title == "" {
+		title = "Imported conversation"
+	}
+	title = truncate("📥 "+title, 100)
+
+	thread, err := s.ThreadStartComplex(parentChannelID, &discordgo.ThreadStart{
+		Name:                title,
+		AutoArchiveDuration: guildCfg.GetAutoArchiveDuration(cfg.Defaults),
+		Type:                discordgo.ChannelTypeGuildPublicThread,
+	})
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to create thread")
+		b.editInteractionError(s, i, "Failed to create conversation thread")
+		return
+	}
+
+	conv := conversation.Conversation{
+		ThreadID:     thread.ID,
+		GuildID:      i.GuildID,
+		ChannelID:    parentChannelID,
+		UserID:       member.User.ID,
+		Model:        modelRef,
+		SystemPrompt: systemPrompt,
+		Title:        title,
+		Retitled:     true, // Imported titles are kept as-is
+		Summary:      transcript.Conversation.Summary,
+		TokenCount:   conversation.TotalTokens(history),
+	}
+
+	if err := b.convManager.Create(ctx, conv); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to save conversation")
+		b.editInteractionError(s, i, "Failed to save imported conversation")
+		s.ChannelDelete(thread.ID)
+		return
+	}
+	if err := b.convManager.ReplaceMessages(ctx, i.GuildID, thread.ID, history); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to save imported messages")
+		b.editInteractionError(s, i, "Failed to save imported conversation")
+		b.convManager.Delete(ctx, i.GuildID, thread.ID)
+		s.ChannelDelete(thread.ID)
+		return
+	}
+
+	intro := fmt.Sprintf("📥 Imported %d message(s) by <@%s> using `%s`. Continue the conversation here.", len(transcript.Messages), member.User.ID, modelRef)
+	if notice != "" {
+		intro += "\n" + notice
+	}
+	s.ChannelMessageSendComplex(thread.ID, &discordgo.MessageSend{
+		Content:         withFooter(intro, conversation.Footer{Model: modelRef}),
+		Components:      conversationButtons(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(fmt.Sprintf("✅ Imported conversation: <#%s>", thread.ID)),
+	})
+
+	b.logger.Info().
+		Str("user", member.User.Username).
+		Str("model", modelRef).
+		Str("thread", thread.ID).
+		Int("messages", len(transcript.Messages)).
+		Msg("Conversation imported")
+}
+
+// readTranscript returns the transcript attached to or pasted into an /import command
+func readTranscript(ctx context.Context, data discordgo.ApplicationCommandInteractionData) ([]byte, error) {
+	if text := getStringOption(data.Options, "transcript"); text != "" {
+		return []byte(text), nil
+	}
+
+	var attachment *discordgo.MessageAttachment
+	for _, opt := range data.Options {
+		if opt.Name == "file" && data.Resolved != nil {
+			if id, ok := opt.Value.(string); ok {
+				attachment = data.Resolved.Attachments[id]
+			}
+		}
+	}
+	if attachment == nil {
+		return nil, fmt.Errorf("attach a transcript file or paste one into the transcript option")
+	}
+	return downloadAttachment(ctx, attachment, maxImportBytes)
+}
+
+// downloadAttachment reads the body of a message attachment of at most limit bytes
+func downloadAttachment(ctx context.Context, attachment *discordgo.MessageAttachment, limit int) ([]byte, error) {
+	if attachment.Size > limit {
+		return nil, fmt.Errorf("file is too large (max %d KiB)", limit/1024)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attachment.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachment URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	if len(raw) > limit {
+		return nil, fmt.Errorf("file is too large (max %d KiB)", limit/1024)
+	}
+
+	return raw, nil
+}
+
+// importSystemPrompt picks the system prompt for an imported conversation
+// Custom prompts from a transcript need manage_prompts; otherwise the guild default is used.
+// Returns a notice for the thread when the transcript's prompt was replaced.
+func (b *Bot) importSystemPrompt(guildCfg *config.GuildConfig, member *discordgo.Member, name, transcriptPrompt string) (string, string, error) {
+	if name != "" {
+		prompt, err := guildCfg.GetSystemPromptByName(name)
+		if err != nil {
+			return "", "", fmt.Errorf("system prompt not found: %s", name)
+		}
+		return prompt, "", nil
+	}
+
+	if transcriptPrompt != "" {
+		// Prompts the guild already offers are always fine
+		for _, sp := range guildCfg.SystemPrompts {
+			if sp.Content == transcriptPrompt {
+				return transcriptPrompt, "", nil
+			}
+		}
+		if b.rbacManager.HasPermission(guildCfg.ID, member, "manage_prompts") {
+			return transcriptPrompt, "", nil
+		}
+	}
+
+	prompt, err := guildCfg.GetDefaultSystemPrompt()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get default system prompt: %w", err)
+	}
+
+	notice := ""
+	if transcriptPrompt != "" {
+		notice = "ℹ️ The transcript's custom system prompt was replaced with this server's default."
+	}
+	return prompt, notice, nil
+}
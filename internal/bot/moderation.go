@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/moderation"
+)
+
+// chat sends a completion request to the model through the guild's moderation pipeline
+func (b *Bot) chat(ctx context.Context, req moderation.Request) (*moderation.Exchange, error) {
+	return b.moderation.Chat(ctx, req, b.llmRegistry.Chat)
+}
+
+// moderationBlockedMessage returns a user-facing message if err is a moderation block
+func moderationBlockedMessage(err error) (string, bool) {
+	var blocked *moderation.BlockedError
+	if !errors.As(err, &blocked) {
+		return "", false
+	}
+
+	if blocked.Stage == moderation.StageInput {
+		return "🛡️ Your message was blocked by moderation.", true
+	}
+	return "🛡️ The response was withheld by moderation.", true
+}
+
+// moderationNotice builds a warning to post alongside a moderated exchange
+func moderationNotice(results ...*moderation.Result) string {
+	var lines []string
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+
+		subject := "Your message"
+		if result.Stage == moderation.StageOutput {
+			subject = "This response"
+		}
+
+		if result.Has(moderation.ActionRedact) {
+			lines = append(lines, fmt.Sprintf("✂️ %s had content redacted by moderation.", subject))
+		}
+		if result.Has(moderation.ActionWarn) {
+			lines = append(lines, fmt.Sprintf("⚠️ %s matched a moderation rule and has been logged.", subject))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// postModerationFlag notifies a guild's moderator channel about flagged content
+func (b *Bot) postModerationFlag(modChannelID string, rec moderation.Record) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚩 **Moderation flag** (%s) in <#%s> by <@%s>\n", rec.Stage, rec.ChannelID, rec.UserID))
+	sb.WriteString(fmt.Sprintf("Rule: `%s` (%s)\n", rec.Rule, rec.Source))
+	sb.WriteString(">>> " + rec.Excerpt)
+
+	// Excerpts are user or model content, so never let them ping anyone
+	_, err := b.session.ChannelMessageSendComplex(modChannelID, &discordgo.MessageSend{
+		Content:         sb.String(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		b.logger.Error().Err(err).Str("channel", modChannelID).Msg("Failed to send moderation flag")
+	}
+}
@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/render"
+)
+
+const (
+	// maxMessageLength is Discord's limit for message content
+	maxMessageLength = render.MessageLimit
+	// historyPageSize is the most messages Discord returns per request
+	historyPageSize = 100
+	// maxHistoryPages caps how much of a thread is read when rebuilding its conversation
+	maxHistoryPages = 10
+	// minReplyPart is the shortest an earlier message of a split reply can be
+	// Split only breaks a reply in the second half of a message, while notices are a line or two.
+	minReplyPart = maxMessageLength / 4
+	// maxReplyAttachmentBytes limits the size of attached replies read back when rebuilding a thread
+	maxReplyAttachmentBytes = 1 << 20 // 1 MiB
+)
+
+// withFooter appends a conversation's metadata footer to a message, if it still fits
+func withFooter(content string, footer conversation.Footer) string {
+	full := content + "\n" + footer.String()
+	if utf8.RuneCountInString(full) > maxMessageLength {
+		return content
+	}
+	return full
+}
+
+// rebuiltThread is the conversation state found in a thread's messages
+type rebuiltThread struct {
+	footer   conversation.Footer
+	owner    string
+	messages []conversation.Message
+}
+
+// recoverConversation rebuilds a conversation from its thread's Discord history
+// Used for threads the bot created whose conversation is neither stored nor archived, e.g. after Redis was flushed.
+// Returns nil if the thread isn't one of the bot's or its history can't be read.
+func (b *Bot) recoverConversation(ctx context.Context, s *discordgo.Session, m *discordgo.MessageCreate) *conversation.Conversation {
+	thread, err := s.State.Channel(m.ChannelID)
+	if err != nil {
+		thread, err = s.Channel(m.ChannelID)
+		if err != nil {
+			return nil
+		}
+	}
+	if !thread.IsThread() || thread.OwnerID != s.State.User.ID {
+		return nil
+	}
+
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(m.GuildID)
+	if err != nil {
+		return nil
+	}
+
+	// Threads started from a message come from /ask and belong to whoever ran the command
+	starter, err := s.ChannelMessage(thread.ParentID, thread.ID)
+	fromMessage := err == nil
+
+	// Read the history before the new message
+	history, err := threadHistory(s, thread.ID, m.ID, guildCfg.GetMessageHistoryLimit(cfg.Defaults))
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", thread.ID).Msg("Failed to read thread history")
+		return nil
+	}
+	readAttachment := func(attachment *discordgo.MessageAttachment) (string, bool) {
+		raw, err := downloadAttachment(ctx, attachment, maxReplyAttachmentBytes)
+		if err != nil {
+			b.logger.Warn().Err(err).Str("thread", thread.ID).Msg("Failed to read attached reply")
+			return "", false
+		}
+		return string(raw), true
+	}
+	rebuilt := rebuildThread(s.State.User.ID, history, fromMessage, readAttachment)
+
+	owner := rebuilt.owner
+	if fromMessage && starter.InteractionMetadata != nil && starter.InteractionMetadata.User != nil {
+		owner = starter.InteractionMetadata.User.ID
+	}
+	if owner == "" {
+		owner = m.Author.ID
+	}
+
+	// Fall back to the guild defaults for anything the footer doesn't name or that is no longer configured
+	model := rebuilt.footer.Model
+	if _, _, err := cfg.ResolveModel(model); model == "" || err != nil {
+		model = guildCfg.DefaultModel
+	}
+
+	systemPrompt := ""
+	if rebuilt.footer.Prompt != "" {
+		systemPrompt, err = guildCfg.GetSystemPromptByName(rebuilt.footer.Prompt)
+	}
+	if systemPrompt == "" || err != nil {
+		systemPrompt, err = guildCfg.GetDefaultSystemPrompt()
+		if err != nil {
+			b.logger.Error().Err(err).Str("guild", m.GuildID).Msg("Failed to get default system prompt")
+			return nil
+		}
+	}
+
+	// Count tokens for the rebuilt history
+	tokenCounter := conversation.NewTokenCounter()
+	messages := append([]conversation.Message{{Role: "system", Content: systemPrompt}}, rebuilt.messages...)
+	for i := range messages {
+		count, err := tokenCounter.Count(messages[i].Content, model)
+		if err != nil {
+			count = len(messages[i].Content) / 4
+		}
+		messages[i].Tokens = count + 4 // Message overhead
+	}
+
+	createdAt, err := discordgo.SnowflakeTimestamp(thread.ID)
+	if err != nil {
+		createdAt = time.Now()
+	}
+
+	conv := conversation.Conversation{
+		ThreadID:     thread.ID,
+		GuildID:      m.GuildID,
+		ChannelID:    thread.ParentID,
+		UserID:       owner,
+		Model:        model,
+		SystemPrompt: systemPrompt,
+		Title:        thread.Name,
+		Retitled:     true, // Keep the thread's current name
+		TokenCount:   conversation.TotalTokens(messages),
+		CreatedAt:    createdAt,
+		UpdatedAt:    time.Now(),
+	}
+
+	if err := b.convManager.Create(ctx, conv); err != nil {
+		b.logger.Error().Err(err).Str("thread", thread.ID).Msg("Failed to save rebuilt conversation")
+		return nil
+	}
+	if err := b.convManager.ReplaceMessages(ctx, m.GuildID, thread.ID, messages); err != nil {
+		b.logger.Error().Err(err).Str("thread", thread.ID).Msg("Failed to save rebuilt messages")
+		b.convManager.Delete(ctx, m.GuildID, thread.ID)
+		return nil
+	}
+
+	sendNotice(s, thread.ID, fmt.Sprintf("🧩 Rebuilt this conversation from %d message(s) in this thread. Context from outside the thread, like the opening /ask prompt, is no longer available.", len(rebuilt.messages)))
+
+	b.logger.Info().
+		Str("guild", m.GuildID).
+		Str("thread", thread.ID).
+		Str("model", model).
+		Int("messages", len(rebuilt.messages)).
+		Msg("Conversation rebuilt from thread history")
+
+	return &conv
+}
+
+// threadHistory reads up to about limit messages posted in a thread before a message, oldest first
+func threadHistory(s *discordgo.Session, threadID, beforeID string, limit int) ([]*discordgo.Message, error) {
+	var history []*discordgo.Message
+	for page := 0; page < maxHistoryPages && len(history) < limit; page++ {
+		msgs, err := s.ChannelMessages(threadID, historyPageSize, beforeID, "", "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get thread messages: %w", err)
+		}
+		history = append(history, msgs...)
+		if len(msgs) < historyPageSize {
+			break
+		}
+		beforeID = msgs[len(msgs)-1].ID
+	}
+
+	// Discord returns the newest messages first
+	slices.Reverse(history)
+	return history, nil
+}
+
+// rebuildThread turns a thread's messages into conversation messages
+// Bot messages with reply buttons are the model's replies, joined with the earlier messages of replies split
+// across several; attached replies are read back with readAttachment. Bot notices and other bots are skipped.
+func rebuildThread(botID string, history []*discordgo.Message, fromMessage bool, readAttachment func(*discordgo.MessageAttachment) (string, bool)) rebuiltThread {
+	var rebuilt rebuiltThread
+	var parts []*discordgo.Message // Earlier messages of the reply being read
+	for _, msg := range history {
+		if msg.Author == nil {
+			continue
+		}
+
+		if msg.Author.ID == botID {
+			footer, _, ok := conversation.ParseFooter(msg.Content)
+			if ok {
+				rebuilt.footer = footer
+
+				// Fork and import threads open with an introduction that mentions their owner, not a reply
+				if !fromMessage {
+					if len(msg.Mentions) > 0 {
+						rebuilt.owner = msg.Mentions[0].ID
+					}
+					parts = nil
+					continue
+				}
+			}
+			if !hasButton(msg, "regenerate") {
+				if isReplyPart(msg) {
+					parts = append(parts, msg)
+				} else {
+					parts = nil
+				}
+				continue
+			}
+			rebuilt.messages = append(rebuilt.messages, rebuiltReply(append(parts, msg), readAttachment))
+			parts = nil
+			continue
+		}
+
+		parts = nil
+		if msg.Author.Bot || msg.Content == "" || (msg.Type != discordgo.MessageTypeDefault && msg.Type != discordgo.MessageTypeReply) {
+			continue
+		}
+		if rebuilt.owner == "" {
+			rebuilt.owner = msg.Author.ID
+		}
+		rebuilt.messages = append(rebuilt.messages, conversation.Message{
+			Role:       "user",
+			Content:    msg.Content,
+			MessageID:  msg.ID,
+			AuthorID:   msg.Author.ID,
+			AuthorName: msg.Author.DisplayName(),
+			SentAt:     msg.Timestamp.UnixMilli(),
+		})
+	}
+	return rebuilt
+}
+
+// rebuiltReply turns the messages a reply was posted as into one assistant message
+// The last message carries the reply's buttons and, for replies too long to post, its attachment.
+func rebuiltReply(msgs []*discordgo.Message, readAttachment func(*discordgo.MessageAttachment) (string, bool)) conversation.Message {
+	ids := make([]string, 0, len(msgs))
+	texts := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+		texts = append(texts, postedText(msg))
+	}
+
+	reply := conversation.Message{
+		Role:    "assistant",
+		Content: render.Join(texts),
+		SentAt:  msgs[0].Timestamp.UnixMilli(),
+	}
+	for _, attachment := range msgs[len(msgs)-1].Attachments {
+		if attachment.Filename != replyAttachmentName {
+			continue
+		}
+		if content, ok := readAttachment(attachment); ok {
+			reply.Content = content
+		}
+	}
+	reply.SetDiscordIDs(ids)
+	return reply
+}
+
+// isReplyPart reports whether a bot message without buttons may be an earlier message of a split reply
+// Those are long, plain messages (or embeds, for embed replies) that aren't interaction responses.
+func isReplyPart(msg *discordgo.Message) bool {
+	if len(msg.Components) > 0 || msg.InteractionMetadata != nil {
+		return false
+	}
+	return len(msg.Embeds) > 0 || utf8.RuneCountInString(msg.Content) >= minReplyPart
+}
+
+// hasButton reports whether a message has a button with the given custom ID
+func hasButton(msg *discordgo.Message, customID string) bool {
+	for _, component := range msg.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if button, ok := c.(*discordgo.Button); ok && button.CustomID == customID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/render"
+)
+
+const (
+	// replyAttachmentName is the file replies too long to post as messages are attached as
+	replyAttachmentName = "reply.md"
+	// maxAttachments is the most files Discord accepts on one message
+	maxAttachments = 10
+	// replyEmbedColor is the accent color of embed replies
+	replyEmbedColor = 0x5865F2
+)
+
+// replyLayout lays out a reply in the guild's reply style, with an optional metadata footer on its last message
+func (b *Bot) replyLayout(guildID string, reply conversation.Message, footer *conversation.Footer) render.Layout {
+	opts := render.Options{Filename: replyAttachmentName}
+
+	cfg := b.GetConfig()
+	if guildCfg, err := cfg.GetGuild(guildID); err == nil {
+		opts.AttachOver = guildCfg.GetReplyAttachmentChars(cfg.Defaults)
+		opts.Escape = render.Escape{
+			MassMentions: guildCfg.Mentions.EscapeMassMentions,
+			Invites:      guildCfg.Mentions.EscapeInvites,
+		}
+		if guildCfg.GetReplyStyle(cfg.Defaults) == config.ReplyStyleEmbed {
+			opts.Embed = true
+			opts.EmbedFooter = replyStats(cfg, reply)
+		}
+	}
+	if footer != nil {
+		opts.Footer = footer.String()
+	}
+
+	return render.Reply(reply.Content, opts)
+}
+
+// replyStats formats the model and usage of a reply for the footer of embed replies
+// Cost is only shown for models with pricing configured.
+func replyStats(cfg *config.Config, reply conversation.Message) string {
+	stats := []string{reply.Model}
+	_, model, err := cfg.ResolveModel(reply.Model)
+	if err == nil {
+		stats[0] = model.DisplayName
+	}
+
+	stats = append(stats, fmt.Sprintf("%d tokens", reply.PromptTokens+reply.CompletionTokens))
+	if err == nil {
+		if cost, ok := model.Cost(reply.PromptTokens, reply.CompletionTokens); ok {
+			stats = append(stats, fmt.Sprintf("$%.4f", cost))
+		}
+	}
+	if reply.LatencyMS > 0 {
+		stats = append(stats, fmt.Sprintf("%.1fs", float64(reply.LatencyMS)/1000))
+	}
+	return strings.Join(stats, " · ")
+}
+
+// replyMentions returns who a guild's replies may ping: nobody unless the guild allows it
+func (b *Bot) replyMentions(guildID string) *discordgo.MessageAllowedMentions {
+	allowed := &discordgo.MessageAllowedMentions{}
+
+	guildCfg, err := b.GetConfig().GetGuild(guildID)
+	if err != nil {
+		return allowed
+	}
+	for _, mention := range guildCfg.Mentions.Allow {
+		switch mention {
+		case config.MentionUsers:
+			allowed.Parse = append(allowed.Parse, discordgo.AllowedMentionTypeUsers)
+		case config.MentionRoles:
+			allowed.Parse = append(allowed.Parse, discordgo.AllowedMentionTypeRoles)
+		case config.MentionEveryone:
+			allowed.Parse = append(allowed.Parse, discordgo.AllowedMentionTypeEveryone)
+		}
+	}
+	return allowed
+}
+
+// postedText returns the text of a posted reply message, from its content or its embed
+func postedText(msg *discordgo.Message) string {
+	_, content, _ := conversation.ParseFooter(msg.Content)
+	for _, embed := range msg.Embeds {
+		content += embed.Description
+	}
+	return content
+}
+
+// messageFooter returns the metadata footer of a posted message, or nil
+func messageFooter(msg *discordgo.Message) *conversation.Footer {
+	if msg == nil {
+		return nil
+	}
+	if footer, _, ok := conversation.ParseFooter(msg.Content); ok {
+		return &footer
+	}
+	return nil
+}
+
+// sendReply posts a laid out reply, reusing the Discord messages it was posted as before
+// Existing messages are edited in order and extra ones are posted after them; the buttons go on the
+// last message, and mentions only ping as the guild allows. Returns the reply's messages, and the existing ones it no longer needs for the caller
+// to delete once the reply is saved.
+func (b *Bot) sendReply(s *discordgo.Session, guildID, channelID string, existing []string, layout render.Layout, components []discordgo.MessageComponent, reference *discordgo.MessageReference) ([]string, []string, error) {
+	mentions := b.replyMentions(guildID)
+
+	var ids []string
+	for n, text := range layout.Messages {
+		// Earlier messages lose any buttons they had
+		last := n == len(layout.Messages)-1
+		msgComponents := []discordgo.MessageComponent{}
+		if last {
+			msgComponents = components
+		}
+		content, embeds := replyMessageContent(layout, text, last)
+
+		if n < len(existing) {
+			noAttachments := []*discordgo.MessageAttachment{}
+			edit := &discordgo.MessageEdit{
+				ID:              existing[n],
+				Channel:         channelID,
+				Content:         &content,
+				Embeds:          &embeds,
+				Components:      &msgComponents,
+				Attachments:     &noAttachments,
+				AllowedMentions: mentions,
+			}
+			if last {
+				edit.Files = replyFiles(layout)
+			}
+			if msg, err := s.ChannelMessageEditComplex(edit); err == nil {
+				ids = append(ids, msg.ID)
+				continue
+			}
+			// The message is gone, post it again
+		}
+
+		send := &discordgo.MessageSend{
+			Content:         content,
+			Embeds:          embeds,
+			Components:      msgComponents,
+			AllowedMentions: mentions,
+		}
+		if last {
+			send.Files = replyFiles(layout)
+		}
+		if n == 0 {
+			send.Reference = reference
+		}
+		msg, err := s.ChannelMessageSendComplex(channelID, send)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to send reply: %w", err)
+		}
+		ids = append(ids, msg.ID)
+	}
+
+	var stale []string
+	if len(existing) > len(layout.Messages) {
+		stale = existing[len(layout.Messages):]
+	}
+	return ids, stale, nil
+}
+
+// replyMessageContent returns the content and embeds of one message of a laid out reply
+// Embeds are always returned, empty for plain replies, so editing a message clears any it had before.
+func replyMessageContent(layout render.Layout, text string, last bool) (string, []*discordgo.MessageEmbed) {
+	embeds := []*discordgo.MessageEmbed{}
+	if !layout.Embed {
+		return text, embeds
+	}
+
+	embed := &discordgo.MessageEmbed{Description: text, Color: replyEmbedColor}
+	content := ""
+	if last {
+		if layout.EmbedFooter != "" {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: layout.EmbedFooter}
+		}
+		content = layout.Footer
+	}
+	return content, append(embeds, embed)
+}
+
+// replyFiles returns the attachment of a laid out reply, if it has one
+func replyFiles(layout render.Layout) []*discordgo.File {
+	if layout.Attachment == "" {
+		return nil
+	}
+	return []*discordgo.File{{
+		Name:        replyAttachmentName,
+		ContentType: "text/markdown",
+		Reader:      strings.NewReader(layout.Attachment),
+	}}
+}
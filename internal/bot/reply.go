@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"strings"
+	"time"
+
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/moderation"
+)
+
+// replyTemperature is the sampling temperature used for conversation replies
+const replyTemperature = 0.7
+
+// replyMessage builds the history entry for a model's reply, recording how it was generated
+// Its Discord messages are set once it has been posted.
+func replyMessage(modelRef string, temperature float64, exchange *moderation.Exchange, content string) conversation.Message {
+	response := exchange.Response
+	provider, _, _ := strings.Cut(modelRef, "/")
+
+	msg := conversation.Message{
+		Role:             "assistant",
+		Content:          content,
+		Tokens:           response.Usage.CompletionTokens,
+		SentAt:           time.Now().UnixMilli(),
+		Model:            modelRef,
+		Provider:         provider,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		LatencyMS:        exchange.Latency.Milliseconds(),
+		Temperature:      &temperature,
+	}
+	if len(response.Choices) > 0 {
+		msg.FinishReason = response.Choices[0].FinishReason
+	}
+	return msg
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/search"
+)
+
+const (
+	// searchResults is the number of threads shown for a /search query
+	searchResults = 10
+	// searchCandidates is how many matches are fetched before filtering by permissions
+	searchCandidates = 50
+	// searchMaxLength keeps the results within Discord's message length limit
+	searchMaxLength = 2000
+)
+
+// handleSearch handles the /search command - finds conversation threads by their messages
+func (b *Bot) handleSearch(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Defer initial response to avoid timeout
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	ctx := context.Background()
+	query := getStringOption(i.ApplicationCommandData().Options, "query")
+
+	if b.search == nil {
+		b.editInteractionError(s, i, "Search is not available")
+		return
+	}
+
+	hits, err := b.search.Search(ctx, i.GuildID, query, searchCandidates)
+	if errors.Is(err, search.ErrEmptyQuery) {
+		b.editInteractionError(s, i, "Search for at least one word longer than a letter that isn't a stop word like \"the\"")
+		return
+	} else if err != nil {
+		b.logger.Error().Err(err).Msg("Search failed")
+		b.editInteractionError(s, i, "Failed to search conversations")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**Search results for** `%s`\n", truncate(strings.ReplaceAll(query, "`", "'"), 100)))
+
+	shown := 0
+	for _, hit := range hits {
+		if shown == searchResults {
+			break
+		}
+
+		thread, ok := b.visibleThread(s, i.Member.User.ID, hit.ThreadID)
+		if !ok {
+			continue
+		}
+
+		title := thread.Name
+		if conv, err := b.convManager.Get(ctx, i.GuildID, hit.ThreadID); err == nil && conv.Title != "" {
+			title = conv.Title
+		}
+		link := fmt.Sprintf("https://discord.com/channels/%s/%s", i.GuildID, hit.ThreadID)
+
+		entry := fmt.Sprintf("\n**[%s](%s)** · %s <t:%d:R>\n> %s\n", escapeLinkText(title), link, hit.Role, hit.Time.Unix(), hit.Snippet)
+		if sb.Len()+len(entry) > searchMaxLength {
+			break
+		}
+		sb.WriteString(entry)
+		shown++
+	}
+
+	if shown == 0 {
+		sb.WriteString("\nNo matching conversations you can see.")
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         stringPtr(sb.String()),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+}
+
+// visibleThread returns a thread if the user can view it
+// Threads inherit visibility from their parent channel; private threads also need membership or Manage Threads
+func (b *Bot) visibleThread(s *discordgo.Session, userID, threadID string) (*discordgo.Channel, bool) {
+	thread, err := s.State.Channel(threadID)
+	if err != nil {
+		thread, err = s.Channel(threadID)
+		if err != nil {
+			// Deleted threads can't be opened anyway
+			return nil, false
+		}
+	}
+
+	perms, err := s.UserChannelPermissions(userID, thread.ParentID)
+	if err != nil || perms&discordgo.PermissionViewChannel == 0 {
+		return nil, false
+	}
+
+	if thread.Type == discordgo.ChannelTypeGuildPrivateThread && perms&discordgo.PermissionManageThreads == 0 {
+		if _, err := s.ThreadMember(threadID, userID, false); err != nil {
+			return nil, false
+		}
+	}
+
+	return thread, true
+}
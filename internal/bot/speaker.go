@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/llm"
+)
+
+// toLLMMessages converts conversation history to provider messages
+// In threads with more than one participant, user messages carry their author using the
+// provider's speaker attribution: the "name" field or a prefix on the content.
+func toLLMMessages(cfg *config.Config, modelRef string, messages []conversation.Message) []llm.Message {
+	llmMessages := make([]llm.Message, len(messages))
+	shared := conversation.MultipleSpeakers(messages)
+
+	for i, msg := range messages {
+		llmMessages[i] = llm.Message{Role: msg.Role, Content: msg.Content}
+		if !shared {
+			continue
+		}
+		llmMessages[i].Content = speakerPrefix(cfg, modelRef, msg) + msg.Content
+
+		if attribution(cfg, modelRef) == config.SpeakerAttributionName && msg.Role == "user" && msg.AuthorID != "" {
+			name := conversation.SpeakerName(msg.AuthorName)
+			if name == "" {
+				name = "user_" + msg.AuthorID
+			}
+			llmMessages[i].Name = name
+		}
+	}
+
+	return llmMessages
+}
+
+// speakerPrefix returns the prefix added to a user message in prefix attribution mode, or ""
+func speakerPrefix(cfg *config.Config, modelRef string, msg conversation.Message) string {
+	if msg.Role != "user" || msg.AuthorName == "" || attribution(cfg, modelRef) != config.SpeakerAttributionPrefix {
+		return ""
+	}
+	provider, _, err := cfg.ResolveModel(modelRef)
+	if err != nil {
+		return ""
+	}
+	return conversation.SpeakerPrefix(provider.GetSpeakerPrefix(), msg.AuthorName)
+}
+
+// attribution returns the speaker attribution mode of a model's provider
+func attribution(cfg *config.Config, modelRef string) string {
+	provider, _, err := cfg.ResolveModel(modelRef)
+	if err != nil {
+		return config.SpeakerAttributionNone
+	}
+	return provider.GetSpeakerAttribution()
+}
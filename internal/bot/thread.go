@@ -2,211 +2,275 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/config"
 	"github.com/s33g/discord-prompter/internal/conversation"
-	"github.com/s33g/discord-prompter/internal/llm"
+	"github.com/s33g/discord-prompter/internal/moderation"
+	"github.com/s33g/discord-prompter/internal/storage"
 )
 
 // handleThreadMessage handles messages in conversation threads
+// Turns in a thread are processed one at a time; messages sent while a reply is being written are answered together.
 func (b *Bot) handleThreadMessage(s *discordgo.Session, m *discordgo.MessageCreate) {
-	ctx := context.Background()
-
-	// Load conversation metadata
-	conv, err := b.convManager.Get(ctx, m.GuildID, m.ChannelID)
-	if err != nil {
-		b.logger.Debug().Err(err).Str("thread", m.ChannelID).Msg("Not a tracked conversation thread")
-		return
-	}
-
-	// Get guild config
-	cfg := b.GetConfig()
-	guildCfg, err := cfg.GetGuild(m.GuildID)
-	if err != nil {
-		b.logger.Error().Err(err).Str("guild", m.GuildID).Msg("Failed to get guild config")
+	if !b.turns.add(m) {
 		return
 	}
 
-	// Get member with roles
-	member, err := s.GuildMember(m.GuildID, m.Author.ID)
-	if err != nil {
-		b.logger.Error().Err(err).Str("user", m.Author.ID).Msg("Failed to get member info")
-		return
-	}
-
-	// Check permissions
-	if !b.rbacManager.HasPermission(m.GuildID, member, "use_models") {
-		s.ChannelMessageSend(m.ChannelID, "❌ You don't have permission to use models")
-		return
+	for batch := b.turns.take(m.ChannelID); batch != nil; batch = b.turns.take(m.ChannelID) {
+		b.handleTurn(s, batch)
 	}
+}
 
-	// Get rate limit config
-	rateLimitCfg := b.getRateLimitForMember(guildCfg, member)
+// handleTurn answers a batch of messages posted in a conversation thread with one reply
+func (b *Bot) handleTurn(s *discordgo.Session, batch []*discordgo.MessageCreate) {
+	ctx := context.Background()
+	guildID, threadID := batch[0].GuildID, batch[0].ChannelID
 
-	// Check rate limits
-	rateResult, err := b.rateLimiter.CheckRateLimit(ctx, m.GuildID, m.Author.ID, rateLimitCfg)
-	if err != nil {
-		b.logger.Error().Err(err).Msg("Rate limit check failed")
-		s.ChannelMessageSend(m.ChannelID, "❌ Failed to check rate limits")
+	// Wait for any turn another replica is processing in this thread
+	unlock, err := b.lockConversation(ctx, guildID, threadID)
+	if errors.Is(err, storage.ErrLockTimeout) {
+		sendNotice(s, threadID, "⏳ The previous reply in this thread is taking too long. Try again in a moment.")
 		return
-	}
-	if !rateResult.Allowed {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("❌ Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
+	} else if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to lock conversation")
 		return
 	}
+	defer unlock()
 
-	// Count tokens in the new message
-	tokenCounter := conversation.NewTokenCounter()
-	userTokens, err := tokenCounter.Count(m.Content, conv.Model)
+	// Load conversation metadata, restoring it from the archive or the thread itself if it expired
+	conv, err := b.convManager.Get(ctx, guildID, threadID)
 	if err != nil {
-		b.logger.Warn().Err(err).Msg("Failed to count tokens, using estimate")
-		userTokens = len(m.Content) / 4
+		conv = b.rehydrateConversation(ctx, s, guildID, threadID)
+		if conv == nil {
+			conv = b.recoverConversation(ctx, s, batch[0])
+		}
+		if conv == nil {
+			b.logger.Debug().Err(err).Str("thread", threadID).Msg("Not a tracked conversation thread")
+			return
+		}
 	}
-	userTokens += 4 // Message overhead
 
-	// Get token limit config
-	tokenLimitCfg := b.getTokenLimitForMember(guildCfg, member)
-
-	// Estimate tokens for the response (reserve 1000)
-	estimatedTokens := userTokens + 1000
-
-	// Check token limits
-	tokenResult, err := b.rateLimiter.CheckTokenLimit(ctx, m.GuildID, m.Author.ID, tokenLimitCfg, estimatedTokens)
+	// Get guild config
+	cfg := b.GetConfig()
+	guildCfg, err := cfg.GetGuild(guildID)
 	if err != nil {
-		b.logger.Error().Err(err).Msg("Token limit check failed")
-		s.ChannelMessageSend(m.ChannelID, "❌ Failed to check token limits")
+		b.logger.Error().Err(err).Str("guild", guildID).Msg("Failed to get guild config")
 		return
 	}
-	if !tokenResult.Allowed {
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("❌ Token limit exceeded. You have %d tokens remaining. Resets in %d seconds.", tokenResult.TokensRemaining, tokenResult.SecondsToReset))
+
+	// Check and moderate each message; rejected ones are answered on their own
+	var accepted []conversation.Message
+	var inputs []*moderation.Result
+	var member *discordgo.Member
+	for _, m := range batch {
+		msg, msgMember, input, ok := b.acceptMessage(ctx, s, guildCfg, conv, m)
+		if !ok {
+			continue
+		}
+		accepted = append(accepted, msg)
+		inputs = append(inputs, input)
+		member = msgMember
+	}
+	if len(accepted) == 0 {
 		return
 	}
 
 	// Show typing indicator
-	s.ChannelTyping(m.ChannelID)
+	s.ChannelTyping(threadID)
 
 	// Load message history
-	messages, err := b.convManager.GetMessages(ctx, m.GuildID, m.ChannelID)
+	history, err := b.convManager.GetMessages(ctx, guildID, threadID)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to load message history")
-		s.ChannelMessageSend(m.ChannelID, "❌ Failed to load conversation history")
+		sendNotice(s, threadID, "❌ Failed to load conversation history")
 		return
 	}
 
-	// Add the new user message to the history
-	newUserMsg := conversation.Message{
-		Role:      "user",
-		Content:   m.Content,
-		Tokens:    userTokens,
-		MessageID: m.ID,
-	}
-	messages = append(messages, newUserMsg)
+	// Add the new user messages to the history
+	messages := append(history[:len(history):len(history)], accepted...)
 
-	// Build context within token limits
-	maxContextTokens := guildCfg.GetMaxContextTokens(cfg.Defaults)
-	reserveTokens := 1000 // Reserve for response
-	builder := conversation.NewContextBuilder(maxContextTokens, reserveTokens)
-	contextMessages, totalContextTokens, err := builder.Build(messages, conv.SystemPrompt, conv.Model)
-	if err != nil {
+	// Build context within token limits using the conversation's context strategy
+	built, maxTokens, err := b.buildContext(ctx, s, cfg, guildCfg, conv, messages, len(history), member)
+	if errors.Is(err, conversation.ErrContextFull) {
+		sendNotice(s, threadID, contextFullMessage)
+		return
+	} else if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to build context")
-		s.ChannelMessageSend(m.ChannelID, "❌ Failed to build conversation context")
+		sendNotice(s, threadID, "❌ Failed to build conversation context")
 		return
 	}
+	contextMessages, totalContextTokens := built.Messages, built.Tokens
 
 	// Convert to LLM messages
-	llmMessages := make([]llm.Message, len(contextMessages))
-	for i, msg := range contextMessages {
-		llmMessages[i] = llm.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
-
-	// Get provider config for max tokens
-	provider, _, _ := cfg.ResolveModel(conv.Model)
-	maxTokens := provider.DefaultMaxTokens
-	if maxTokens == 0 {
-		maxTokens = 2048
-	}
+	llmMessages := toLLMMessages(cfg, conv.Model, contextMessages)
 
 	// Call LLM
 	b.logger.Info().
-		Str("user", m.Author.Username).
+		Str("user", member.User.Username).
 		Str("model", conv.Model).
-		Str("thread", m.ChannelID).
+		Str("thread", threadID).
+		Int("new_messages", len(accepted)).
 		Int("context_messages", len(contextMessages)).
 		Int("context_tokens", totalContextTokens).
+		Int("max_tokens", maxTokens).
 		Msg("Calling LLM")
 
-	response, err := b.llmRegistry.Chat(ctx, conv.Model, llmMessages, maxTokens, 0.7)
+	exchange, err := b.chat(ctx, moderation.Request{
+		Subject:     moderation.Subject{GuildID: guildID, ChannelID: threadID, UserID: member.User.ID},
+		ModelRef:    conv.Model,
+		Messages:    llmMessages,
+		MaxTokens:   maxTokens,
+		Temperature: replyTemperature,
+		SkipInput:   true, // Each message was moderated when it was accepted
+	})
 	if err != nil {
+		if msg, ok := moderationBlockedMessage(err); ok {
+			sendNotice(s, threadID, msg)
+			return
+		}
 		b.logger.Error().Err(err).Msg("LLM request failed")
-		s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("❌ Failed to get response from %s: %v", conv.Model, err))
+		sendNotice(s, threadID, fmt.Sprintf("❌ Failed to get response from %s: %v", conv.Model, err))
 		return
 	}
+	response := exchange.Response
 
 	if len(response.Choices) == 0 {
-		s.ChannelMessageSend(m.ChannelID, "❌ No response from model")
+		sendNotice(s, threadID, "❌ No response from model")
 		return
 	}
 
 	assistantContent := response.Choices[0].Message.Content
 
-	// Post response with buttons
-	msg, err := s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
-		Content: assistantContent,
-		Components: []discordgo.MessageComponent{
-			discordgo.ActionsRow{
-				Components: []discordgo.MessageComponent{
-					discordgo.Button{
-						Label:    "🔄 Regenerate",
-						Style:    discordgo.PrimaryButton,
-						CustomID: "regenerate",
-					},
-					discordgo.Button{
-						Label:    "📋 Copy",
-						Style:    discordgo.SecondaryButton,
-						CustomID: "copy",
-					},
-					discordgo.Button{
-						Label:    "🗑️ Clear Context",
-						Style:    discordgo.DangerButton,
-						CustomID: "clear",
-					},
-					discordgo.Button{
-						Label:    "⚙️ Settings",
-						Style:    discordgo.SecondaryButton,
-						CustomID: "settings",
-					},
-				},
-			},
-		},
-	})
-
+	// Post response with buttons, split across messages if it's long
+	reply := replyMessage(conv.Model, replyTemperature, exchange, assistantContent)
+	ids, _, err := b.sendReply(s, guildID, threadID, nil, b.replyLayout(guildID, reply, nil), replyButtons(reply), nil)
 	if err != nil {
 		b.logger.Error().Err(err).Msg("Failed to send message")
 		return
 	}
+	reply.SetDiscordIDs(ids)
 
-	// Save both user and assistant messages
-	b.convManager.AddMessage(ctx, m.GuildID, m.ChannelID, newUserMsg)
-	b.convManager.AddMessage(ctx, m.GuildID, m.ChannelID, conversation.Message{
-		Role:      "assistant",
-		Content:   assistantContent,
-		Tokens:    response.Usage.CompletionTokens,
-		MessageID: msg.ID,
-	})
+	// Save the user messages and the assistant reply
+	for _, userMsg := range accepted {
+		b.convManager.AddMessage(ctx, guildID, threadID, userMsg)
+	}
+	b.convManager.AddMessage(ctx, guildID, threadID, reply)
 
 	// Update conversation token count
 	conv.TokenCount += response.Usage.TotalTokens
-	b.convManager.Update(ctx, *conv)
+	b.convManager.IncrementTokenCount(ctx, guildID, threadID, response.Usage.TotalTokens)
+
+	// Let the thread know if moderation changed or logged anything
+	if notice := moderationNotice(append(inputs, exchange.Output)...); notice != "" {
+		sendNotice(s, threadID, notice)
+	}
+
+	// Retitle the thread if the topic has drifted since it was created
+	messages = append(messages, conversation.Message{Role: "assistant", Content: assistantContent})
+	b.maybeRetitle(ctx, s, cfg, guildCfg, conv, messages, member)
 
 	b.logger.Info().
-		Str("user", m.Author.Username).
+		Str("user", member.User.Username).
 		Str("model", conv.Model).
-		Str("thread", m.ChannelID).
+		Str("thread", threadID).
 		Int("tokens", response.Usage.TotalTokens).
 		Int("total_tokens", conv.TokenCount).
 		Msg("Response sent")
 }
+
+// acceptMessage checks a thread message's author against permissions and limits, and moderates it
+// Returns false, after telling the author why, if the message shouldn't be sent to the model.
+func (b *Bot) acceptMessage(ctx context.Context, s *discordgo.Session, guildCfg *config.GuildConfig, conv *conversation.Conversation, m *discordgo.MessageCreate) (conversation.Message, *discordgo.Member, *moderation.Result, bool) {
+	var none conversation.Message
+
+	// Get member with roles
+	member, err := s.GuildMember(m.GuildID, m.Author.ID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("user", m.Author.ID).Msg("Failed to get member info")
+		return none, nil, nil, false
+	}
+
+	// Check permissions
+	if !b.rbacManager.HasPermission(m.GuildID, member, "use_models") {
+		sendNotice(s, m.ChannelID, "❌ You don't have permission to use models")
+		return none, nil, nil, false
+	}
+
+	// Check the thread's posting policy
+	if !conv.CanPost(m.Author.ID) {
+		s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Content:         fmt.Sprintf("🔒 <@%s> hasn't allowed others to post in this conversation. Your message won't be sent to the model.", conv.UserID),
+			Reference:       m.Reference(),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		return none, nil, nil, false
+	}
+
+	// Get rate limit config
+	rateLimitCfg := b.getRateLimitForMember(guildCfg, member)
+
+	// Check rate limits
+	rateResult, err := b.rateLimiter.CheckRateLimit(ctx, m.GuildID, m.Author.ID, rateLimitCfg)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Rate limit check failed")
+		sendNotice(s, m.ChannelID, "❌ Failed to check rate limits")
+		return none, nil, nil, false
+	}
+	if !rateResult.Allowed {
+		sendNotice(s, m.ChannelID, fmt.Sprintf("❌ Rate limited. Try again in %d seconds.", rateResult.SecondsToReset))
+		return none, nil, nil, false
+	}
+
+	// Count tokens in the new message
+	tokenCounter := conversation.NewTokenCounter()
+	userTokens, err := tokenCounter.Count(m.Content, conv.Model)
+	if err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to count tokens, using estimate")
+		userTokens = len(m.Content) / 4
+	}
+	userTokens += 4 // Message overhead
+
+	// Get token limit config
+	tokenLimitCfg := b.getTokenLimitForMember(guildCfg, member)
+
+	// Estimate tokens for the response (reserve 1000)
+	estimatedTokens := userTokens + 1000
+
+	// Check token limits
+	tokenResult, err := b.rateLimiter.CheckTokenLimit(ctx, m.GuildID, m.Author.ID, tokenLimitCfg, estimatedTokens)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Token limit check failed")
+		sendNotice(s, m.ChannelID, "❌ Failed to check token limits")
+		return none, nil, nil, false
+	}
+	if !tokenResult.Allowed {
+		sendNotice(s, m.ChannelID, fmt.Sprintf("❌ Token limit exceeded. You have %d tokens remaining. Resets in %d seconds.", tokenResult.TokensRemaining, tokenResult.SecondsToReset))
+		return none, nil, nil, false
+	}
+
+	// Moderate the message before it joins the turn, storing the moderated (possibly redacted) version
+	subject := moderation.Subject{GuildID: m.GuildID, ChannelID: m.ChannelID, UserID: m.Author.ID}
+	input, err := b.moderation.Check(ctx, subject, moderation.StageInput, m.Content)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Moderation check failed")
+		sendNotice(s, m.ChannelID, "❌ Failed to run moderation checks")
+		return none, nil, nil, false
+	}
+	if input.Blocked() {
+		sendNotice(s, m.ChannelID, "🛡️ Your message was blocked by moderation.")
+		return none, nil, nil, false
+	}
+
+	return conversation.Message{
+		Role:       "user",
+		Content:    input.Content,
+		Tokens:     userTokens,
+		MessageID:  m.ID,
+		AuthorID:   m.Author.ID,
+		AuthorName: member.DisplayName(),
+		SentAt:     m.Timestamp.UnixMilli(),
+	}, member, input, true
+}
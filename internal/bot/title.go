@@ -0,0 +1,125 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/conversation"
+	"github.com/s33g/discord-prompter/internal/llm"
+)
+
+// titleResult carries a generated thread title and what it cost
+type titleResult struct {
+	title string
+	usage llm.Usage
+	err   error
+}
+
+// generateTitleAsync generates a thread title in the background so it can run alongside the main completion
+// Its usage is charged to the member even if the caller bails out before reading the result.
+func (b *Bot) generateTitleAsync(ctx context.Context, guildCfg *config.GuildConfig, member *discordgo.Member, titleModel, prompt string) <-chan titleResult {
+	// Buffered so the goroutine never blocks if the caller bails out early
+	ch := make(chan titleResult, 1)
+
+	go func() {
+		title, usage, err := b.llmRegistry.GenerateTitle(ctx, titleModel, prompt)
+		b.recordExtraTokens(ctx, guildCfg, member, usage)
+		ch <- titleResult{title: title, usage: usage, err: err}
+	}()
+
+	return ch
+}
+
+// fallbackTitle derives a thread title from the prompt itself
+func fallbackTitle(prompt string) string {
+	return truncate(prompt, 80)
+}
+
+// maybeRetitle re-checks a thread's title once the conversation has had enough turns to drift
+func (b *Bot) maybeRetitle(ctx context.Context, s *discordgo.Session, cfg *config.Config, guildCfg *config.GuildConfig, conv *conversation.Conversation, messages []conversation.Message, member *discordgo.Member) {
+	turns := guildCfg.GetRetitleAfterTurns(cfg.Defaults)
+	if conv.Retitled || turns <= 0 {
+		return
+	}
+
+	userTurns := 0
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			userTurns++
+		}
+	}
+	if userTurns < turns {
+		return
+	}
+
+	titleModel := guildCfg.GetTitleModel(cfg.Defaults, conv.Model)
+	title, usage, err := b.llmRegistry.SuggestTitle(ctx, titleModel, conv.Title, titleTranscript(messages))
+
+	// Only try once per conversation, even if the model call failed
+	conv.Retitled = true
+	conv.TokenCount += usage.TotalTokens
+
+	if err != nil {
+		b.logger.Warn().Err(err).Str("thread", conv.ThreadID).Msg("Failed to re-check thread title")
+	} else if title != "" && !strings.EqualFold(title, conv.Title) {
+		if _, err := s.ChannelEdit(conv.ThreadID, &discordgo.ChannelEdit{Name: title}); err != nil {
+			b.logger.Warn().Err(err).Str("thread", conv.ThreadID).Msg("Failed to rename thread")
+		} else {
+			b.logger.Info().
+				Str("thread", conv.ThreadID).
+				Str("old_title", conv.Title).
+				Str("new_title", title).
+				Msg("Thread retitled")
+			conv.Title = title
+		}
+	}
+
+	b.convManager.MarkRetitled(ctx, conv.GuildID, conv.ThreadID, conv.Title)
+	b.convManager.IncrementTokenCount(ctx, conv.GuildID, conv.ThreadID, usage.TotalTokens)
+	b.recordExtraTokens(ctx, guildCfg, member, usage)
+}
+
+// recordExtraTokens counts background usage (titles, summaries) toward the member's token limit
+func (b *Bot) recordExtraTokens(ctx context.Context, guildCfg *config.GuildConfig, member *discordgo.Member, usage llm.Usage) {
+	if usage.TotalTokens == 0 {
+		return
+	}
+
+	tokenLimitCfg := b.getTokenLimitForMember(guildCfg, member)
+	if err := b.rateLimiter.AddTokens(ctx, guildCfg.ID, member.User.ID, tokenLimitCfg, usage.TotalTokens); err != nil {
+		b.logger.Warn().Err(err).Msg("Failed to record token usage")
+	}
+}
+
+// titleTranscript renders recent messages compactly for title suggestions
+func titleTranscript(messages []conversation.Message) string {
+	const maxMessages = 10
+
+	start := 0
+	if len(messages) > maxMessages {
+		start = len(messages) - maxMessages
+	}
+
+	return renderTranscript(messages[start:], 500)
+}
+
+// renderTranscript renders messages as "role: content" lines, skipping system prompts
+// Each message is truncated to maxChars (0 = no limit)
+func renderTranscript(messages []conversation.Message, maxChars int) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
+
+		content := msg.Content
+		if maxChars > 0 {
+			content = truncate(content, maxChars)
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, content))
+	}
+	return sb.String()
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+const (
+	// conversationLockTTL is how long a conversation's lock outlives a holder that died without releasing it
+	// Live holders extend it every third of the TTL for as long as their turn runs.
+	conversationLockTTL = 30 * time.Second
+	// conversationLockWait bounds how long to wait for a turn in progress
+	// It covers context compaction, moderation and the LLM call, which times out after two minutes.
+	conversationLockWait = 5 * time.Minute
+)
+
+// turnQueue serializes turns per thread within this process
+// Messages that arrive while a reply is being written are queued and answered together in the next turn.
+type turnQueue struct {
+	mu      sync.Mutex
+	pending map[string][]*discordgo.MessageCreate // Present while a thread has a worker
+}
+
+// newTurnQueue creates an empty turn queue
+func newTurnQueue() *turnQueue {
+	return &turnQueue{pending: make(map[string][]*discordgo.MessageCreate)}
+}
+
+// add queues a message and reports whether the caller should start working on its thread
+// Returns false if another worker is already processing the thread and will pick the message up.
+func (q *turnQueue) add(m *discordgo.MessageCreate) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, active := q.pending[m.ChannelID]
+	q.pending[m.ChannelID] = append(queued, m)
+	return !active
+}
+
+// take returns all queued messages for a thread as one batch
+// When nothing is queued the thread is marked idle and nil is returned, ending the worker.
+func (q *turnQueue) take(threadID string) []*discordgo.MessageCreate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batch := q.pending[threadID]
+	if len(batch) == 0 {
+		delete(q.pending, threadID)
+		return nil
+	}
+	q.pending[threadID] = nil
+	return batch
+}
+
+// lockConversation waits for exclusive access to a conversation across all bot replicas
+// The lock is kept alive until the returned function releases it.
+func (b *Bot) lockConversation(ctx context.Context, guildID, threadID string) (func(), error) {
+	waitCtx, cancel := context.WithTimeout(ctx, conversationLockWait)
+	defer cancel()
+
+	lock, err := b.storage.Lock(waitCtx, b.storage.Keys().ConversationLock(guildID, threadID), conversationLockTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extend the lease while the turn runs, however long the model takes
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(conversationLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := lock.Extend(context.Background(), conversationLockTTL)
+				if errors.Is(err, storage.ErrLockLost) {
+					b.logger.Warn().Str("thread", threadID).Msg("Conversation lock expired during turn")
+					return
+				} else if err != nil {
+					b.logger.Warn().Err(err).Str("thread", threadID).Msg("Failed to extend conversation lock")
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		if err := lock.Unlock(context.Background()); err != nil {
+			b.logger.Warn().Err(err).Str("thread", threadID).Msg("Failed to release conversation lock")
+		}
+	}, nil
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+)
+
+// handleUndoButton removes the last exchange from the conversation history and deletes its reply
+// Only the conversation owner or members who can manage prompts may undo.
+func (b *Bot) handleUndoButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// Defer initial response; waiting for the conversation lock can take a while
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	// Get member
+	member, err := s.GuildMember(i.GuildID, i.Member.User.ID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to get member info")
+		return
+	}
+
+	// Wait for any turn in progress so the exchange being undone is complete
+	unlock, err := b.lockConversation(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to lock conversation")
+		b.editInteractionError(s, i, "Another reply in this thread is still being written")
+		return
+	}
+	defer unlock()
+
+	// Check permissions (only the conversation owner or admins can undo)
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.editInteractionError(s, i, "Failed to load conversation")
+		return
+	}
+	if conv.UserID != member.User.ID && !b.rbacManager.HasPermission(i.GuildID, member, "manage_prompts") {
+		b.editInteractionError(s, i, "You can only undo your own conversations")
+		return
+	}
+
+	// Load message history
+	messages, err := b.convManager.GetMessages(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load messages")
+		b.editInteractionError(s, i, "Failed to load message history")
+		return
+	}
+
+	// Only the latest exchange can be undone, from its own reply
+	start := conversation.LastExchange(messages)
+	undone := messages[start:]
+	if !slices.ContainsFunc(undone, func(msg conversation.Message) bool { return msg.HasDiscordID(i.Message.ID) }) {
+		b.editInteractionError(s, i, "Only the latest exchange can be undone")
+		return
+	}
+
+	if err := b.convManager.ReplaceMessages(ctx, i.GuildID, threadID, messages[:start]); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to undo exchange")
+		b.editInteractionError(s, i, "Failed to update conversation history")
+		return
+	}
+
+	// Delete the reply; prompts belong to their authors and stay in the thread
+	prompts := 0
+	for _, msg := range undone {
+		if msg.Role != "assistant" {
+			prompts++
+			continue
+		}
+		for _, id := range msg.DiscordIDs() {
+			s.ChannelMessageDelete(threadID, id)
+		}
+	}
+
+	s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: stringPtr(fmt.Sprintf("↩️ Undone. The reply was deleted and %d prompt(s) above it are no longer part of the conversation.", prompts)),
+	})
+
+	b.logger.Info().
+		Str("user", member.User.Username).
+		Str("action", "undo").
+		Str("thread", threadID).
+		Int("removed", len(undone)).
+		Msg("Exchange undone")
+}
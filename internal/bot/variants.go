@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/s33g/discord-prompter/internal/conversation"
+)
+
+// replyComponents returns the components for a stored reply posted as the given Discord message
+// Regenerated replies get buttons on the second row to page through their variants.
+// Variant custom IDs have the form "variant:<messageID>:<index>".
+func replyComponents(reply conversation.Message, messageID string) []discordgo.MessageComponent {
+	components := replyButtons(reply)
+	count := reply.VariantCount()
+	if count < 2 {
+		return components
+	}
+
+	navigation := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "◀",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("variant:%s:%d", messageID, reply.Variant-1),
+			Disabled: reply.Variant == 0,
+		},
+		discordgo.Button{
+			Label:    fmt.Sprintf("%d/%d", reply.Variant+1, count),
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("variant:%s:current", messageID),
+			Disabled: true,
+		},
+		discordgo.Button{
+			Label:    "▶",
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("variant:%s:%d", messageID, reply.Variant+1),
+			Disabled: reply.Variant+1 >= count,
+		},
+	}
+
+	// Share the second row with Continue and Download code
+	if len(components) > 1 {
+		row := components[1].(discordgo.ActionsRow)
+		row.Components = append(row.Components, navigation...)
+		components[1] = row
+		return components
+	}
+	return append(components, discordgo.ActionsRow{Components: navigation})
+}
+
+// showReply re-posts a reply's selected variant over the Discord messages it was posted as
+// Messages the variant no longer needs are returned for the caller to delete once the reply is saved.
+func (b *Bot) showReply(s *discordgo.Session, guildID, threadID string, clicked *discordgo.Message, reply *conversation.Message) ([]string, error) {
+	ids := reply.DiscordIDs()
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("reply has no Discord message")
+	}
+
+	// Keep the metadata footer of a thread's first reply, which is on the message with the buttons
+	current := clicked
+	if current == nil || !reply.HasDiscordID(current.ID) {
+		current, _ = s.ChannelMessage(threadID, ids[len(ids)-1])
+	}
+
+	layout := b.replyLayout(guildID, *reply, messageFooter(current))
+	posted, stale, err := b.sendReply(s, guildID, threadID, ids, layout, replyComponents(*reply, ids[0]), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	reply.SetDiscordIDs(posted)
+	return stale, nil
+}
+
+// handleVariantButton switches a regenerated reply to another of its variants
+// The selected variant is the one the model sees in later turns.
+func (b *Bot) handleVariantButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 3 {
+		b.respondError(s, i, "Invalid variant")
+		return
+	}
+	index, err := strconv.Atoi(parts[2])
+	if err != nil {
+		b.respondError(s, i, "Invalid variant")
+		return
+	}
+
+	// Acknowledge the interaction
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+
+	ctx := context.Background()
+	threadID := i.ChannelID
+
+	// Wait for any turn in progress so the history isn't rewritten underneath it
+	unlock, err := b.lockConversation(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("thread", threadID).Msg("Failed to lock conversation")
+		sendNotice(s, threadID, "❌ Another reply in this thread is still being written")
+		return
+	}
+	defer unlock()
+
+	// Switching variants changes what the model sees, so it takes the same rights as regenerating
+	conv, err := b.convManager.Get(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load conversation")
+		sendNotice(s, threadID, "❌ Failed to load conversation")
+		return
+	}
+	if !b.rbacManager.HasPermission(i.GuildID, i.Member, "use_models") {
+		sendNotice(s, threadID, "❌ You don't have permission to use models")
+		return
+	}
+	if !conv.CanPost(i.Member.User.ID) {
+		sendNotice(s, threadID, "❌ Only people who can post in this conversation can switch its replies")
+		return
+	}
+
+	// Load message history
+	messages, err := b.convManager.GetMessages(ctx, i.GuildID, threadID)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to load messages")
+		sendNotice(s, threadID, "❌ Failed to load message history")
+		return
+	}
+
+	idx := conversation.FindMessage(messages, parts[1])
+	if idx < 0 || messages[idx].Role != "assistant" {
+		sendNotice(s, threadID, "❌ This reply is no longer in the conversation history")
+		return
+	}
+	reply := messages[idx]
+	if !reply.SelectVariant(index) {
+		sendNotice(s, threadID, "❌ That variant no longer exists")
+		return
+	}
+
+	staleParts, err := b.showReply(s, i.GuildID, threadID, i.Message, &reply)
+	if err != nil {
+		b.logger.Error().Err(err).Msg("Failed to show variant")
+		sendNotice(s, threadID, "❌ Failed to show that variant")
+		return
+	}
+
+	if err := b.convManager.SetMessage(ctx, i.GuildID, threadID, idx, reply); err != nil {
+		b.logger.Error().Err(err).Msg("Failed to save selected variant")
+		return
+	}
+	for _, id := range staleParts {
+		s.ChannelMessageDelete(threadID, id)
+	}
+
+	b.logger.Debug().
+		Str("thread", threadID).
+		Int("variant", reply.Variant+1).
+		Int("variants", reply.VariantCount()).
+		Msg("Reply variant selected")
+}
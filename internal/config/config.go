@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 
 	"gopkg.in/yaml.v3"
 )
@@ -44,6 +45,21 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("redis.address is required")
 	}
 
+	// Validate archive
+	if c.Archive.Enabled {
+		if c.Archive.Driver != "postgres" {
+			return fmt.Errorf("archive.driver must be postgres, got %q", c.Archive.Driver)
+		}
+		if c.Archive.DSNEnv == "" {
+			return fmt.Errorf("archive.dsn_env is required")
+		}
+		switch c.Archive.GetMode() {
+		case ArchiveWriteThrough, ArchiveOnExpiry:
+		default:
+			return fmt.Errorf("archive.mode must be write_through or on_expiry, got %q", c.Archive.Mode)
+		}
+	}
+
 	// Validate providers
 	if len(c.Providers) == 0 {
 		return fmt.Errorf("at least one provider is required")
@@ -60,6 +76,11 @@ func (c *Config) Validate() error {
 		if len(provider.Models) == 0 {
 			return fmt.Errorf("provider[%d] must have at least one model", i)
 		}
+		switch provider.GetSpeakerAttribution() {
+		case SpeakerAttributionName, SpeakerAttributionPrefix, SpeakerAttributionNone:
+		default:
+			return fmt.Errorf("provider[%d].speaker_attribution must be name, prefix or none, got %q", i, provider.SpeakerAttribution)
+		}
 
 		for j, model := range provider.Models {
 			if model.ID == "" {
@@ -68,6 +89,9 @@ func (c *Config) Validate() error {
 			if model.DisplayName == "" {
 				return fmt.Errorf("provider[%d].models[%d].display_name is required", i, j)
 			}
+			if model.InputCostPerMillion < 0 || model.OutputCostPerMillion < 0 {
+				return fmt.Errorf("provider[%d].models[%d] costs cannot be negative", i, j)
+			}
 
 			// Track provider/model combinations
 			fullID := fmt.Sprintf("%s/%s", provider.Name, model.ID)
@@ -75,6 +99,31 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate default title model
+	if c.Defaults.TitleModel != "" && !providerModels[c.Defaults.TitleModel] {
+		return fmt.Errorf("defaults.title_model references unknown model: %s", c.Defaults.TitleModel)
+	}
+
+	// Validate default summary model
+	if c.Defaults.SummaryModel != "" && !providerModels[c.Defaults.SummaryModel] {
+		return fmt.Errorf("defaults.summary_model references unknown model: %s", c.Defaults.SummaryModel)
+	}
+
+	// Validate default context strategy
+	if c.Defaults.ContextStrategy != "" && !ValidContextStrategy(c.Defaults.ContextStrategy) {
+		return fmt.Errorf("defaults.context_strategy is invalid: %s", c.Defaults.ContextStrategy)
+	}
+
+	// Validate reply attachment threshold
+	if c.Defaults.ReplyAttachmentChars < 0 {
+		return fmt.Errorf("defaults.reply_attachment_chars cannot be negative")
+	}
+
+	// Validate default reply style
+	if c.Defaults.ReplyStyle != "" && !ValidReplyStyle(c.Defaults.ReplyStyle) {
+		return fmt.Errorf("defaults.reply_style is invalid: %s", c.Defaults.ReplyStyle)
+	}
+
 	// Validate guilds
 	if len(c.Guilds) == 0 {
 		return fmt.Errorf("at least one guild is required")
@@ -110,6 +159,43 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("guilds[%d].default_model must be in enabled_models", i)
 		}
 
+		// Validate history limit
+		if guild.MessageHistoryLimit != nil && *guild.MessageHistoryLimit <= 0 {
+			return fmt.Errorf("guilds[%d].message_history_limit must be positive", i)
+		}
+
+		// Validate reply attachment threshold
+		if guild.ReplyAttachmentChars != nil && *guild.ReplyAttachmentChars < 0 {
+			return fmt.Errorf("guilds[%d].reply_attachment_chars cannot be negative", i)
+		}
+
+		// Validate reply style
+		if guild.ReplyStyle != "" && !ValidReplyStyle(guild.ReplyStyle) {
+			return fmt.Errorf("guilds[%d].reply_style is invalid: %s", i, guild.ReplyStyle)
+		}
+
+		// Validate mention types
+		for _, mention := range guild.Mentions.Allow {
+			if !ValidMentionType(mention) {
+				return fmt.Errorf("guilds[%d].mentions.allow has unknown mention type: %s", i, mention)
+			}
+		}
+
+		// Validate title model references a valid provider
+		if guild.TitleModel != "" && !providerModels[guild.TitleModel] {
+			return fmt.Errorf("guilds[%d].title_model references unknown model: %s", i, guild.TitleModel)
+		}
+
+		// Validate summary model references a valid provider
+		if guild.SummaryModel != "" && !providerModels[guild.SummaryModel] {
+			return fmt.Errorf("guilds[%d].summary_model references unknown model: %s", i, guild.SummaryModel)
+		}
+
+		// Validate context strategy
+		if guild.ContextStrategy != "" && !ValidContextStrategy(guild.ContextStrategy) {
+			return fmt.Errorf("guilds[%d].context_strategy is invalid: %s", i, guild.ContextStrategy)
+		}
+
 		// Validate system prompts
 		if len(guild.SystemPrompts) == 0 {
 			return fmt.Errorf("guilds[%d] must have at least one system prompt", i)
@@ -119,11 +205,93 @@ func (c *Config) Validate() error {
 		if len(guild.RBAC.Roles) == 0 {
 			return fmt.Errorf("guilds[%d].rbac.roles is required", i)
 		}
+
+		// Validate moderation
+		if err := guild.Moderation.validate(); err != nil {
+			return fmt.Errorf("guilds[%d].moderation: %w", i, err)
+		}
 	}
 
 	return nil
 }
 
+// validate checks moderation endpoint and rule settings
+func (m *ModerationConfig) validate() error {
+	if ep := m.Endpoint; ep != nil {
+		if ep.BaseURL == "" {
+			return fmt.Errorf("endpoint.base_url is required")
+		}
+		switch ep.Action {
+		case "block", "warn", "flag":
+		default:
+			return fmt.Errorf("endpoint.action must be block, warn or flag, got %q", ep.Action)
+		}
+		if !validModerationApply(ep.Apply) {
+			return fmt.Errorf("endpoint.apply must be input, output or both, got %q", ep.Apply)
+		}
+	}
+
+	for j, rule := range m.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("rules[%d].name is required", j)
+		}
+		if rule.Pattern == "" && len(rule.Keywords) == 0 {
+			return fmt.Errorf("rules[%d] needs a pattern or keywords", j)
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("rules[%d].pattern is invalid: %w", j, err)
+			}
+		}
+		switch rule.Action {
+		case "block", "warn", "redact", "flag":
+		default:
+			return fmt.Errorf("rules[%d].action must be block, warn, redact or flag, got %q", j, rule.Action)
+		}
+		if !validModerationApply(rule.Apply) {
+			return fmt.Errorf("rules[%d].apply must be input, output or both, got %q", j, rule.Apply)
+		}
+	}
+
+	return nil
+}
+
+func validModerationApply(apply string) bool {
+	switch apply {
+	case "", "input", "output", "both":
+		return true
+	}
+	return false
+}
+
+// ValidContextStrategy reports whether name is a known context window strategy
+// Kept in sync with the strategies in the conversation package
+func ValidContextStrategy(name string) bool {
+	switch name {
+	case "truncate", "pin_first", "sliding_window", "summary", "error":
+		return true
+	}
+	return false
+}
+
+// ValidReplyStyle reports whether style is a known reply style
+func ValidReplyStyle(style string) bool {
+	switch style {
+	case ReplyStyleText, ReplyStyleEmbed:
+		return true
+	}
+	return false
+}
+
+// ValidMentionType reports whether mention is a type replies can be allowed to ping
+func ValidMentionType(mention string) bool {
+	switch mention {
+	case MentionUsers, MentionRoles, MentionEveryone:
+		return true
+	}
+	return false
+}
+
 // GetGuild returns the configuration for a specific guild ID
 func (c *Config) GetGuild(guildID string) (*GuildConfig, error) {
 	for i := range c.Guilds {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"math"
 	"os"
 	"testing"
 )
@@ -167,6 +168,187 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid moderation rule pattern",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:    "test",
+						BaseURL: "http://localhost",
+						Models:  []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+				Guilds: []GuildConfig{
+					{
+						ID:            "123",
+						EnabledModels: []string{"test/model1"},
+						DefaultModel:  "test/model1",
+						SystemPrompts: []SystemPrompt{{Name: "default", Content: "Test"}},
+						RBAC:          RBACConfig{Roles: []RoleConfig{{DiscordRole: "Admin"}}},
+						Moderation: ModerationConfig{
+							Enabled: true,
+							Rules:   []ModerationRule{{Name: "bad", Pattern: "([", Action: "block"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid moderation action",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:    "test",
+						BaseURL: "http://localhost",
+						Models:  []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+				Guilds: []GuildConfig{
+					{
+						ID:            "123",
+						EnabledModels: []string{"test/model1"},
+						DefaultModel:  "test/model1",
+						SystemPrompts: []SystemPrompt{{Name: "default", Content: "Test"}},
+						RBAC:          RBACConfig{Roles: []RoleConfig{{DiscordRole: "Admin"}}},
+						Moderation: ModerationConfig{
+							Enabled: true,
+							Rules:   []ModerationRule{{Name: "words", Keywords: []string{"foo"}, Action: "ban"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid context strategy",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:    "test",
+						BaseURL: "http://localhost",
+						Models:  []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+				Guilds: []GuildConfig{
+					{
+						ID:              "123",
+						EnabledModels:   []string{"test/model1"},
+						DefaultModel:    "test/model1",
+						ContextStrategy: "forget_everything",
+						SystemPrompts:   []SystemPrompt{{Name: "default", Content: "Test"}},
+						RBAC:            RBACConfig{Roles: []RoleConfig{{DiscordRole: "Admin"}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid reply style",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:    "test",
+						BaseURL: "http://localhost",
+						Models:  []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+				Guilds: []GuildConfig{
+					{
+						ID:            "123",
+						EnabledModels: []string{"test/model1"},
+						DefaultModel:  "test/model1",
+						ReplyStyle:    "hologram",
+						SystemPrompts: []SystemPrompt{{Name: "default", Content: "Test"}},
+						RBAC:          RBACConfig{Roles: []RoleConfig{{DiscordRole: "Admin"}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown mention type",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:    "test",
+						BaseURL: "http://localhost",
+						Models:  []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+				Guilds: []GuildConfig{
+					{
+						ID:            "123",
+						EnabledModels: []string{"test/model1"},
+						DefaultModel:  "test/model1",
+						Mentions:      MentionsConfig{Allow: []string{"users", "channels"}},
+						SystemPrompts: []SystemPrompt{{Name: "default", Content: "Test"}},
+						RBAC:          RBACConfig{Roles: []RoleConfig{{DiscordRole: "Admin"}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "non-positive guild history limit",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:    "test",
+						BaseURL: "http://localhost",
+						Models:  []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+				Guilds: []GuildConfig{
+					{
+						ID:                  "123",
+						EnabledModels:       []string{"test/model1"},
+						DefaultModel:        "test/model1",
+						MessageHistoryLimit: new(int),
+						SystemPrompts:       []SystemPrompt{{Name: "default", Content: "Test"}},
+						RBAC:                RBACConfig{Roles: []RoleConfig{{DiscordRole: "Admin"}}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid speaker attribution",
+			config: &Config{
+				Redis: RedisConfig{Address: "localhost:6379"},
+				Providers: []Provider{
+					{
+						Name:               "test",
+						BaseURL:            "http://localhost",
+						SpeakerAttribution: "nickname",
+						Models:             []Model{{ID: "model1", DisplayName: "Model 1"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "archive without dsn env",
+			config: &Config{
+				Redis:   RedisConfig{Address: "localhost:6379"},
+				Archive: ArchiveConfig{Enabled: true, Driver: "postgres"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid archive mode",
+			config: &Config{
+				Redis:   RedisConfig{Address: "localhost:6379"},
+				Archive: ArchiveConfig{Enabled: true, Driver: "postgres", DSNEnv: "ARCHIVE_DSN", Mode: "sometimes"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -292,3 +474,134 @@ func TestGuildConfig_GetDefaultSystemPrompt(t *testing.T) {
 		})
 	}
 }
+
+func TestGuildConfig_GetTitleModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		guild    GuildConfig
+		defaults DefaultsConfig
+		want     string
+	}{
+		{
+			name:  "conversation model fallback",
+			guild: GuildConfig{},
+			want:  "openai/gpt-4o",
+		},
+		{
+			name:     "default title model",
+			guild:    GuildConfig{},
+			defaults: DefaultsConfig{TitleModel: "openai/gpt-4o-mini"},
+			want:     "openai/gpt-4o-mini",
+		},
+		{
+			name:     "guild override",
+			guild:    GuildConfig{TitleModel: "ollama/llama3.2"},
+			defaults: DefaultsConfig{TitleModel: "openai/gpt-4o-mini"},
+			want:     "ollama/llama3.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.guild.GetTitleModel(tt.defaults, "openai/gpt-4o")
+			if got != tt.want {
+				t.Errorf("GetTitleModel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuildConfig_GetMessageHistoryLimit(t *testing.T) {
+	defaults := DefaultsConfig{MessageHistoryLimit: 50}
+
+	if got := (&GuildConfig{}).GetMessageHistoryLimit(defaults); got != 50 {
+		t.Errorf("GetMessageHistoryLimit() = %v, want 50", got)
+	}
+
+	limit := 200
+	if got := (&GuildConfig{MessageHistoryLimit: &limit}).GetMessageHistoryLimit(defaults); got != 200 {
+		t.Errorf("GetMessageHistoryLimit() = %v, want 200", got)
+	}
+}
+
+func TestGuildConfig_GetReplyAttachmentChars(t *testing.T) {
+	defaults := DefaultsConfig{ReplyAttachmentChars: 8000}
+
+	if got := (&GuildConfig{}).GetReplyAttachmentChars(defaults); got != 8000 {
+		t.Errorf("GetReplyAttachmentChars() = %v, want 8000", got)
+	}
+
+	// Zero turns attachments off for the guild
+	if got := (&GuildConfig{ReplyAttachmentChars: new(int)}).GetReplyAttachmentChars(defaults); got != 0 {
+		t.Errorf("GetReplyAttachmentChars() = %v, want 0", got)
+	}
+}
+
+func TestGuildConfig_GetReplyStyle(t *testing.T) {
+	if got := (&GuildConfig{}).GetReplyStyle(DefaultsConfig{}); got != ReplyStyleText {
+		t.Errorf("GetReplyStyle() = %v, want %v", got, ReplyStyleText)
+	}
+	if got := (&GuildConfig{}).GetReplyStyle(DefaultsConfig{ReplyStyle: ReplyStyleEmbed}); got != ReplyStyleEmbed {
+		t.Errorf("GetReplyStyle() = %v, want %v", got, ReplyStyleEmbed)
+	}
+	if got := (&GuildConfig{ReplyStyle: ReplyStyleText}).GetReplyStyle(DefaultsConfig{ReplyStyle: ReplyStyleEmbed}); got != ReplyStyleText {
+		t.Errorf("GetReplyStyle() = %v, want %v", got, ReplyStyleText)
+	}
+}
+
+func TestModel_Cost(t *testing.T) {
+	if _, ok := (&Model{ID: "llama3.2"}).Cost(1000, 1000); ok {
+		t.Error("Models without pricing should have no cost")
+	}
+
+	model := Model{ID: "gpt-4o", InputCostPerMillion: 2.5, OutputCostPerMillion: 10}
+	cost, ok := model.Cost(2000, 500)
+	if !ok || math.Abs(cost-0.01) > 1e-9 {
+		t.Errorf("Cost() = %v, %v, want 0.01", cost, ok)
+	}
+}
+
+func TestGuildConfig_GetContextBudget(t *testing.T) {
+	guildMax := 16000
+
+	tests := []struct {
+		name  string
+		guild GuildConfig
+		model *Model
+		want  int
+	}{
+		{
+			name:  "unknown window uses default",
+			guild: GuildConfig{},
+			model: &Model{ID: "m"},
+			want:  4096,
+		},
+		{
+			name:  "nil model uses default",
+			guild: GuildConfig{},
+			want:  4096,
+		},
+		{
+			name:  "smaller model window wins",
+			guild: GuildConfig{MaxContextTokens: &guildMax},
+			model: &Model{ID: "m", ContextWindow: 8192},
+			want:  8192,
+		},
+		{
+			name:  "guild policy caps large window",
+			guild: GuildConfig{MaxContextTokens: &guildMax},
+			model: &Model{ID: "m", ContextWindow: 128000},
+			want:  16000,
+		},
+	}
+
+	defaults := DefaultsConfig{MaxContextTokens: 4096}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.guild.GetContextBudget(defaults, tt.model)
+			if got != tt.want {
+				t.Errorf("GetContextBudget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -14,6 +14,11 @@ func DefaultConfig() *Config {
 			UsageRetentionDays:       90,  // 3 months
 			MessageHistoryLimit:      50,
 			ThreadAutoArchiveMinutes: 60,
+			RetitleAfterTurns:        4,
+			ContextStrategy:          "truncate",
+			SlidingWindowTurns:       10,
+			ReplyAttachmentChars:     8000,
+			ReplyStyle:               ReplyStyleText,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -9,6 +9,7 @@ import (
 type Config struct {
 	Discord   DiscordConfig  `yaml:"discord"`
 	Redis     RedisConfig    `yaml:"redis"`
+	Archive   ArchiveConfig  `yaml:"archive,omitempty"`
 	Defaults  DefaultsConfig `yaml:"defaults"`
 	Providers []Provider     `yaml:"providers"`
 	Guilds    []GuildConfig  `yaml:"guilds"`
@@ -29,13 +30,52 @@ type RedisConfig struct {
 	KeyPrefix   string `yaml:"key_prefix"`
 }
 
+// ArchiveConfig holds settings for the durable conversation archive
+type ArchiveConfig struct {
+	Enabled              bool   `yaml:"enabled"`
+	Driver               string `yaml:"driver"`                           // "postgres"
+	DSNEnv               string `yaml:"dsn_env"`                          // Environment variable holding the connection string
+	Mode                 string `yaml:"mode,omitempty"`                   // "write_through" (default) or "on_expiry"
+	SweepIntervalMinutes int    `yaml:"sweep_interval_minutes,omitempty"` // How often on_expiry looks for expiring conversations
+}
+
+// Archive modes
+const (
+	ArchiveWriteThrough = "write_through"
+	ArchiveOnExpiry     = "on_expiry"
+)
+
+// GetMode returns the archive mode (write-through unless configured otherwise)
+func (a *ArchiveConfig) GetMode() string {
+	if a.Mode == "" {
+		return ArchiveWriteThrough
+	}
+	return a.Mode
+}
+
+// SweepInterval returns how often expiring conversations are archived in on_expiry mode
+func (a *ArchiveConfig) SweepInterval() time.Duration {
+	minutes := a.SweepIntervalMinutes
+	if minutes <= 0 {
+		minutes = 10
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
 // DefaultsConfig holds default values applied to all guilds
 type DefaultsConfig struct {
-	MaxContextTokens         int `yaml:"max_context_tokens"`
-	ConversationTTLHours     int `yaml:"conversation_ttl_hours"`
-	UsageRetentionDays       int `yaml:"usage_retention_days"`
-	MessageHistoryLimit      int `yaml:"message_history_limit"`
-	ThreadAutoArchiveMinutes int `yaml:"thread_auto_archive_minutes"`
+	MaxContextTokens         int    `yaml:"max_context_tokens"`
+	ConversationTTLHours     int    `yaml:"conversation_ttl_hours"`
+	UsageRetentionDays       int    `yaml:"usage_retention_days"`
+	MessageHistoryLimit      int    `yaml:"message_history_limit"`
+	ThreadAutoArchiveMinutes int    `yaml:"thread_auto_archive_minutes"`
+	TitleModel               string `yaml:"title_model,omitempty"`          // Model used for thread titles (empty = conversation model)
+	RetitleAfterTurns        int    `yaml:"retitle_after_turns,omitempty"`  // Re-check the title after this many user turns (0 = never)
+	ContextStrategy          string `yaml:"context_strategy,omitempty"`     // How history is fit into the context window (truncate, pin_first, sliding_window, summary, error)
+	SlidingWindowTurns       int    `yaml:"sliding_window_turns,omitempty"` // Turns kept by the sliding_window strategy
+	SummaryModel             string `yaml:"summary_model,omitempty"`        // Model used for compaction summaries (empty = conversation model)
+	ReplyAttachmentChars     int    `yaml:"reply_attachment_chars"`         // Replies longer than this are attached as a file instead of split (0 = always split)
+	ReplyStyle               string `yaml:"reply_style,omitempty"`          // How replies are posted: text, or embed with a model and usage footer
 }
 
 // ConversationTTL returns the conversation TTL as a Duration
@@ -45,20 +85,76 @@ func (d *DefaultsConfig) ConversationTTL() time.Duration {
 
 // Provider represents an LLM provider configuration
 type Provider struct {
-	Name             string  `yaml:"name"`
-	BaseURL          string  `yaml:"base_url"`
-	APIKeyEnv        string  `yaml:"api_key_env"`
-	DefaultMaxTokens int     `yaml:"default_max_tokens"`
-	Models           []Model `yaml:"models"`
+	Name               string  `yaml:"name"`
+	BaseURL            string  `yaml:"base_url"`
+	APIKeyEnv          string  `yaml:"api_key_env"`
+	DefaultMaxTokens   int     `yaml:"default_max_tokens"`
+	SpeakerAttribution string  `yaml:"speaker_attribution,omitempty"` // "name" (default), "prefix" or "none"
+	SpeakerPrefix      string  `yaml:"speaker_prefix,omitempty"`      // Prefix format for "prefix", e.g. "{name}: "
+	Models             []Model `yaml:"models"`
+}
+
+// Speaker attribution modes for messages in shared threads
+const (
+	SpeakerAttributionName   = "name"   // OpenAI-style "name" field on user messages
+	SpeakerAttributionPrefix = "prefix" // Prepend speaker_prefix to the message content
+	SpeakerAttributionNone   = "none"
+)
+
+// DefaultSpeakerPrefix is used when speaker_attribution is "prefix" and no speaker_prefix is set
+const DefaultSpeakerPrefix = "{name}: "
+
+// GetSpeakerAttribution returns how message authors are passed to this provider
+func (p *Provider) GetSpeakerAttribution() string {
+	if p.SpeakerAttribution == "" {
+		return SpeakerAttributionName
+	}
+	return p.SpeakerAttribution
+}
+
+// GetSpeakerPrefix returns the prefix format used when speaker_attribution is "prefix"
+func (p *Provider) GetSpeakerPrefix() string {
+	if p.SpeakerPrefix == "" {
+		return DefaultSpeakerPrefix
+	}
+	return p.SpeakerPrefix
+}
+
+// DefaultResponseTokens caps responses when a provider doesn't set default_max_tokens
+const DefaultResponseTokens = 2048
+
+// GetDefaultMaxTokens returns the response token cap for this provider
+func (p *Provider) GetDefaultMaxTokens() int {
+	if p.DefaultMaxTokens > 0 {
+		return p.DefaultMaxTokens
+	}
+	return DefaultResponseTokens
 }
 
 // Model represents an LLM model configuration
 type Model struct {
-	ID            string `yaml:"id"`
-	DisplayName   string `yaml:"display_name"`
-	ContextWindow int    `yaml:"context_window"`
+	ID                   string  `yaml:"id"`
+	DisplayName          string  `yaml:"display_name"`
+	ContextWindow        int     `yaml:"context_window"`
+	InputCostPerMillion  float64 `yaml:"input_cost_per_million,omitempty"`  // Price of a million prompt tokens, for cost estimates
+	OutputCostPerMillion float64 `yaml:"output_cost_per_million,omitempty"` // Price of a million completion tokens
 }
 
+// Cost estimates the price of a request from its token usage
+// Returns false if the model has no pricing configured.
+func (m *Model) Cost(promptTokens, completionTokens int) (float64, bool) {
+	if m.InputCostPerMillion == 0 && m.OutputCostPerMillion == 0 {
+		return 0, false
+	}
+	return (float64(promptTokens)*m.InputCostPerMillion + float64(completionTokens)*m.OutputCostPerMillion) / 1_000_000, true
+}
+
+// Reply styles control how replies are posted
+const (
+	ReplyStyleText  = "text"  // Plain message content (default)
+	ReplyStyleEmbed = "embed" // Embeds with the model, tokens, cost and latency in their footer
+)
+
 // GuildConfig holds per-guild configuration
 type GuildConfig struct {
 	ID                   string            `yaml:"id"`
@@ -69,12 +165,36 @@ type GuildConfig struct {
 	MaxContextTokens     *int              `yaml:"max_context_tokens,omitempty"`
 	ConversationTTLHours *int              `yaml:"conversation_ttl_hours,omitempty"`
 	UsageRetentionDays   *int              `yaml:"usage_retention_days,omitempty"`
+	MessageHistoryLimit  *int              `yaml:"message_history_limit,omitempty"`
+	TitleModel           string            `yaml:"title_model,omitempty"`
+	RetitleAfterTurns    *int              `yaml:"retitle_after_turns,omitempty"`
+	ContextStrategy      string            `yaml:"context_strategy,omitempty"`
+	SlidingWindowTurns   *int              `yaml:"sliding_window_turns,omitempty"`
+	SummaryModel         string            `yaml:"summary_model,omitempty"`
+	ReplyAttachmentChars *int              `yaml:"reply_attachment_chars,omitempty"`
+	ReplyStyle           string            `yaml:"reply_style,omitempty"`
 	SystemPrompts        []SystemPrompt    `yaml:"system_prompts"`
 	RBAC                 RBACConfig        `yaml:"rbac"`
 	RateLimits           RateLimitsConfig  `yaml:"rate_limits"`
 	TokenLimits          TokenLimitsConfig `yaml:"token_limits"`
+	Moderation           ModerationConfig  `yaml:"moderation,omitempty"`
+	Mentions             MentionsConfig    `yaml:"mentions,omitempty"`
+}
+
+// MentionsConfig controls what replies may ping and how model output is escaped
+type MentionsConfig struct {
+	Allow              []string `yaml:"allow,omitempty"`                // Mention types replies may ping: users, roles, everyone (default none)
+	EscapeMassMentions bool     `yaml:"escape_mass_mentions,omitempty"` // Defuse @everyone, @here and role mentions in model output
+	EscapeInvites      bool     `yaml:"escape_invites,omitempty"`       // Show Discord invite links in model output as code instead of links
 }
 
+// Mention types that replies can be allowed to ping
+const (
+	MentionUsers    = "users"
+	MentionRoles    = "roles"
+	MentionEveryone = "everyone"
+)
+
 // SystemPrompt represents a system prompt template
 type SystemPrompt struct {
 	Name    string `yaml:"name"`
@@ -119,6 +239,34 @@ type TokenLimit struct {
 	PeriodHours     int  `yaml:"period_hours,omitempty"`
 }
 
+// ModerationConfig holds prompt and response moderation settings
+type ModerationConfig struct {
+	Enabled      bool                `yaml:"enabled"`
+	Endpoint     *ModerationEndpoint `yaml:"endpoint,omitempty"`
+	Rules        []ModerationRule    `yaml:"rules,omitempty"`
+	ModChannelID string              `yaml:"mod_channel_id,omitempty"` // Channel receiving "flag" notifications
+	AuditLimit   int                 `yaml:"audit_limit,omitempty"`    // Max audit records kept per guild
+}
+
+// ModerationEndpoint configures an OpenAI-compatible /moderations endpoint
+type ModerationEndpoint struct {
+	BaseURL    string   `yaml:"base_url"`
+	APIKeyEnv  string   `yaml:"api_key_env"`
+	Model      string   `yaml:"model,omitempty"`
+	Action     string   `yaml:"action"`               // block, warn or flag
+	Categories []string `yaml:"categories,omitempty"` // Empty = any flagged category
+	Apply      string   `yaml:"apply,omitempty"`      // input, output or both (default)
+}
+
+// ModerationRule defines a local regex or keyword rule
+type ModerationRule struct {
+	Name     string   `yaml:"name"`
+	Pattern  string   `yaml:"pattern,omitempty"`  // Regular expression
+	Keywords []string `yaml:"keywords,omitempty"` // Case-insensitive whole words
+	Action   string   `yaml:"action"`             // block, warn, redact or flag
+	Apply    string   `yaml:"apply,omitempty"`    // input, output or both (default)
+}
+
 // LoggingConfig holds logging settings
 type LoggingConfig struct {
 	Level  string `yaml:"level"`
@@ -133,6 +281,16 @@ func (g *GuildConfig) GetMaxContextTokens(defaults DefaultsConfig) int {
 	return defaults.MaxContextTokens
 }
 
+// GetContextBudget returns the context window available for a model
+// The model's own window is used when known, capped by the guild's max_context_tokens
+func (g *GuildConfig) GetContextBudget(defaults DefaultsConfig, model *Model) int {
+	budget := g.GetMaxContextTokens(defaults)
+	if model != nil && model.ContextWindow > 0 && (budget <= 0 || model.ContextWindow < budget) {
+		budget = model.ContextWindow
+	}
+	return budget
+}
+
 // GetConversationTTL returns the conversation TTL duration for this guild
 func (g *GuildConfig) GetConversationTTL(defaults DefaultsConfig) time.Duration {
 	hours := defaults.ConversationTTLHours
@@ -142,6 +300,33 @@ func (g *GuildConfig) GetConversationTTL(defaults DefaultsConfig) time.Duration
 	return time.Duration(hours) * time.Hour
 }
 
+// GetMessageHistoryLimit returns the number of messages kept per conversation for this guild
+func (g *GuildConfig) GetMessageHistoryLimit(defaults DefaultsConfig) int {
+	if g.MessageHistoryLimit != nil {
+		return *g.MessageHistoryLimit
+	}
+	return defaults.MessageHistoryLimit
+}
+
+// GetReplyAttachmentChars returns the reply length above which replies are attached as a file for this guild
+func (g *GuildConfig) GetReplyAttachmentChars(defaults DefaultsConfig) int {
+	if g.ReplyAttachmentChars != nil {
+		return *g.ReplyAttachmentChars
+	}
+	return defaults.ReplyAttachmentChars
+}
+
+// GetReplyStyle returns how replies are posted for this guild
+func (g *GuildConfig) GetReplyStyle(defaults DefaultsConfig) string {
+	if g.ReplyStyle != "" {
+		return g.ReplyStyle
+	}
+	if defaults.ReplyStyle != "" {
+		return defaults.ReplyStyle
+	}
+	return ReplyStyleText
+}
+
 // GetUsageRetentionDays returns the usage retention days for this guild
 func (g *GuildConfig) GetUsageRetentionDays(defaults DefaultsConfig) int {
 	if g.UsageRetentionDays != nil {
@@ -150,6 +335,54 @@ func (g *GuildConfig) GetUsageRetentionDays(defaults DefaultsConfig) int {
 	return defaults.UsageRetentionDays
 }
 
+// GetTitleModel returns the model used to generate thread titles
+// Falls back to the conversation's own model when no title model is configured
+func (g *GuildConfig) GetTitleModel(defaults DefaultsConfig, conversationModel string) string {
+	if g.TitleModel != "" {
+		return g.TitleModel
+	}
+	if defaults.TitleModel != "" {
+		return defaults.TitleModel
+	}
+	return conversationModel
+}
+
+// GetRetitleAfterTurns returns after how many user turns a thread title is re-checked (0 = never)
+func (g *GuildConfig) GetRetitleAfterTurns(defaults DefaultsConfig) int {
+	if g.RetitleAfterTurns != nil {
+		return *g.RetitleAfterTurns
+	}
+	return defaults.RetitleAfterTurns
+}
+
+// GetContextStrategy returns the strategy used to fit history into the context window
+func (g *GuildConfig) GetContextStrategy(defaults DefaultsConfig) string {
+	if g.ContextStrategy != "" {
+		return g.ContextStrategy
+	}
+	return defaults.ContextStrategy
+}
+
+// GetSlidingWindowTurns returns how many turns the sliding_window strategy keeps
+func (g *GuildConfig) GetSlidingWindowTurns(defaults DefaultsConfig) int {
+	if g.SlidingWindowTurns != nil {
+		return *g.SlidingWindowTurns
+	}
+	return defaults.SlidingWindowTurns
+}
+
+// GetSummaryModel returns the model used to summarize compacted history
+// Falls back to the conversation's own model when no summary model is configured
+func (g *GuildConfig) GetSummaryModel(defaults DefaultsConfig, conversationModel string) string {
+	if g.SummaryModel != "" {
+		return g.SummaryModel
+	}
+	if defaults.SummaryModel != "" {
+		return defaults.SummaryModel
+	}
+	return conversationModel
+}
+
 // GetDefaultSystemPrompt returns the default system prompt for this guild
 func (g *GuildConfig) GetDefaultSystemPrompt() (string, error) {
 	// Use explicit default if set
@@ -187,6 +420,14 @@ func (g *GuildConfig) GetSystemPromptByName(name string) (string, error) {
 	return "", fmt.Errorf("system prompt '%s' not found", name)
 }
 
+// GetModerationAuditLimit returns how many moderation audit records to keep
+func (g *GuildConfig) GetModerationAuditLimit() int {
+	if g.Moderation.AuditLimit > 0 {
+		return g.Moderation.AuditLimit
+	}
+	return 1000
+}
+
 // GetAutoArchiveDuration returns the thread auto archive duration in minutes
 func (g *GuildConfig) GetAutoArchiveDuration(defaults DefaultsConfig) int {
 	return defaults.ThreadAutoArchiveMinutes
@@ -5,77 +5,141 @@ type ContextBuilder struct {
 	counter       *TokenCounter
 	maxTokens     int
 	reserveTokens int // Reserve for response
+	strategy      Strategy
 }
 
 // NewContextBuilder creates a new context builder
+// Uses the truncate strategy unless another one is set with WithStrategy
 func NewContextBuilder(maxTokens, reserveTokens int) *ContextBuilder {
 	return &ContextBuilder{
 		counter:       NewTokenCounter(),
 		maxTokens:     maxTokens,
 		reserveTokens: reserveTokens,
+		strategy:      TruncateStrategy{},
 	}
 }
 
+// WithStrategy sets the strategy used to select history
+func (cb *ContextBuilder) WithStrategy(strategy Strategy) *ContextBuilder {
+	cb.strategy = strategy
+	return cb
+}
+
+// BuildResult holds the outcome of building a context window
+type BuildResult struct {
+	Messages []Message // System prompt, optional summary, then history in chronological order
+	Tokens   int
+	Dropped  []Message // History messages left out of the context, in chronological order
+	Overflow int       // Number of leading input messages left out (what can be compacted away)
+}
+
+// SummaryPrefix introduces the rolling summary injected after the system prompt
+const SummaryPrefix = "Summary of the earlier conversation:\n"
+
 // Build creates a context from messages, truncating if necessary
 // Returns messages that fit within the token budget
 func (cb *ContextBuilder) Build(messages []Message, systemPrompt, model string) ([]Message, int, error) {
+	result, err := cb.BuildWithSummary(messages, "", systemPrompt, model)
+	if err != nil {
+		return nil, 0, err
+	}
+	return result.Messages, result.Tokens, nil
+}
+
+// BuildWithSummary creates a context from messages with an optional rolling summary
+// of compacted history placed right after the system prompt. History is selected by
+// the builder's strategy; what doesn't make it in is reported so the caller can compact it.
+func (cb *ContextBuilder) BuildWithSummary(messages []Message, summary, systemPrompt, model string) (*BuildResult, error) {
 	availableTokens := cb.maxTokens - cb.reserveTokens
 
 	// System prompt always included
 	systemTokens, err := cb.counter.Count(systemPrompt, model)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	systemTokens += 4 // Message formatting overhead
 
-	remainingTokens := availableTokens - systemTokens
-	if remainingTokens <= 0 {
-		// System prompt alone exceeds budget
-		return []Message{
-			{Role: "system", Content: systemPrompt, Tokens: systemTokens},
-		}, systemTokens, nil
-	}
-
-	// Build result from newest to oldest, then reverse
-	result := []Message{
+	header := []Message{
 		{Role: "system", Content: systemPrompt, Tokens: systemTokens},
 	}
 	totalTokens := systemTokens
 
-	// Process messages in reverse (keep newest)
-	for i := len(messages) - 1; i >= 0; i-- {
-		msg := messages[i]
+	// Rolling summary of compacted history, if it fits
+	if summary != "" {
+		summaryContent := SummaryPrefix + summary
+		summaryTokens, err := cb.counter.Count(summaryContent, model)
+		if err != nil {
+			return nil, err
+		}
+		summaryTokens += 4
+
+		if totalTokens+summaryTokens <= availableTokens {
+			header = append(header, Message{Role: "system", Content: summaryContent, Tokens: summaryTokens})
+			totalTokens += summaryTokens
+		}
+	}
 
-		// Count tokens for this message
-		msgTokens := msg.Tokens
-		if msgTokens == 0 {
-			// Count if not pre-counted
+	// Collect history with token counts, remembering each message's input index
+	history := make([]Message, 0, len(messages))
+	positions := make([]int, 0, len(messages))
+	for i, msg := range messages {
+		// Stored system prompts are superseded by the current one
+		if msg.Role == "system" {
+			continue
+		}
+
+		// Count if not pre-counted
+		if msg.Tokens == 0 {
 			count, err := cb.counter.Count(msg.Content, model)
 			if err != nil {
-				return nil, 0, err
+				return nil, err
 			}
-			msgTokens = count + 4 // Message formatting
+			msg.Tokens = count + 4 // Message formatting
 		}
 
-		// Check if message fits
-		if totalTokens+msgTokens > availableTokens {
-			// Can't fit more messages
-			break
+		history = append(history, msg)
+		positions = append(positions, i)
+	}
+
+	budget := availableTokens - totalTokens
+	var kept []int
+	if budget > 0 {
+		kept, err = cb.strategy.Select(history, budget)
+		if err != nil {
+			return nil, err
+		}
+	} else if len(history) > 0 {
+		// System prompt alone exceeds budget
+		if _, ok := cb.strategy.(ErrorStrategy); ok {
+			return nil, ErrContextFull
 		}
+	}
 
-		// Add message (will reverse later)
-		result = append(result, msg)
-		totalTokens += msgTokens
+	result := &BuildResult{Messages: header}
+	keep := make(map[int]bool, len(kept))
+	for _, idx := range kept {
+		keep[idx] = true
+		result.Messages = append(result.Messages, history[idx])
+		totalTokens += history[idx].Tokens
 	}
+	result.Tokens = totalTokens
 
-	// Reverse messages (except system prompt at index 0)
-	if len(result) > 1 {
-		for i, j := 1, len(result)-1; i < j; i, j = i+1, j-1 {
-			result[i], result[j] = result[j], result[i]
+	// Report what was left out
+	result.Overflow = len(messages)
+	for idx, msg := range history {
+		if keep[idx] {
+			if positions[idx] < result.Overflow {
+				result.Overflow = positions[idx]
+			}
+			continue
 		}
+		result.Dropped = append(result.Dropped, msg)
+	}
+	if len(result.Dropped) == 0 {
+		result.Overflow = 0
 	}
 
-	return result, totalTokens, nil
+	return result, nil
 }
 
 // CountTokens counts tokens for a message
@@ -93,6 +93,75 @@ func TestContextBuilder_SystemPromptAlwaysIncluded(t *testing.T) {
 	}
 }
 
+func TestContextBuilder_BuildWithSummary(t *testing.T) {
+	cb := NewContextBuilder(100, 20)
+
+	messages := []Message{
+		{Role: "system", Content: "Stored system prompt", Tokens: 10},
+		{Role: "user", Content: "Old message", Tokens: 30},
+		{Role: "assistant", Content: "Old response", Tokens: 30},
+		{Role: "user", Content: "New message", Tokens: 20},
+	}
+
+	result, err := cb.BuildWithSummary(messages, "User wants X.", "System.", "gpt-4")
+	if err != nil {
+		t.Fatalf("BuildWithSummary() error = %v", err)
+	}
+
+	// System prompt first, then summary
+	if result.Messages[0].Content != "System." {
+		t.Errorf("First message = %q, want system prompt", result.Messages[0].Content)
+	}
+	if result.Messages[1].Role != "system" || result.Messages[1].Content != SummaryPrefix+"User wants X." {
+		t.Errorf("Second message = %+v, want summary", result.Messages[1])
+	}
+
+	// Stored system prompts are never repeated
+	for _, msg := range result.Messages[2:] {
+		if msg.Role == "system" {
+			t.Errorf("Unexpected system message in history: %q", msg.Content)
+		}
+	}
+
+	// Newest message kept, overflow reported as a chronological prefix
+	last := result.Messages[len(result.Messages)-1]
+	if last.Content != "New message" {
+		t.Errorf("Last message = %q, want newest", last.Content)
+	}
+	if len(result.Dropped) == 0 {
+		t.Fatal("Expected dropped messages")
+	}
+	if result.Dropped[0].Content != "Old message" {
+		t.Errorf("Dropped should start with the oldest message, got %q", result.Dropped[0].Content)
+	}
+	if messages[result.Overflow].Content != result.Messages[2].Content {
+		t.Errorf("Overflow = %d, should point at the first kept message", result.Overflow)
+	}
+	if result.Tokens > 100-20 {
+		t.Errorf("Total tokens %d exceeds budget %d", result.Tokens, 100-20)
+	}
+}
+
+func TestContextBuilder_NoDroppedWhenEverythingFits(t *testing.T) {
+	cb := NewContextBuilder(1000, 100)
+
+	messages := []Message{
+		{Role: "user", Content: "Hello", Tokens: 5},
+		{Role: "assistant", Content: "Hi", Tokens: 5},
+	}
+
+	result, err := cb.BuildWithSummary(messages, "", "System.", "gpt-4")
+	if err != nil {
+		t.Fatalf("BuildWithSummary() error = %v", err)
+	}
+	if len(result.Dropped) != 0 {
+		t.Errorf("Dropped = %d messages, want 0", len(result.Dropped))
+	}
+	if len(result.Messages) != 3 {
+		t.Errorf("Messages = %d, want 3", len(result.Messages))
+	}
+}
+
 func TestTokenCounter_Count(t *testing.T) {
 	tc := NewTokenCounter()
 
@@ -0,0 +1,214 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// TranscriptVersion is the current version of the JSON transcript schema
+// Bump it whenever fields are renamed or removed so importers can tell formats apart
+const TranscriptVersion = 1
+
+// Export formats
+const (
+	ExportMarkdown = "markdown"
+	ExportJSON     = "json"
+	ExportHTML     = "html"
+)
+
+// Transcript is a portable snapshot of a conversation and its history
+type Transcript struct {
+	Version      int                    `json:"version"`
+	ExportedAt   time.Time              `json:"exported_at"`
+	Conversation TranscriptConversation `json:"conversation"`
+	Messages     []Message              `json:"messages"`
+}
+
+// TranscriptConversation holds the conversation metadata included in a transcript
+type TranscriptConversation struct {
+	ThreadID     string    `json:"thread_id"`
+	GuildID      string    `json:"guild_id"`
+	ChannelID    string    `json:"channel_id"`
+	UserID       string    `json:"user_id"`
+	Title        string    `json:"title"`
+	Model        string    `json:"model"`
+	SystemPrompt string    `json:"system_prompt"`
+	Summary      string    `json:"summary,omitempty"`
+	ParentID     string    `json:"parent_id,omitempty"`
+	TokenCount   int       `json:"token_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// NewTranscript builds a transcript from a conversation and its messages
+// Stored system prompts are left out of the messages since the conversation carries the current one
+func NewTranscript(conv Conversation, messages []Message, exportedAt time.Time) Transcript {
+	history := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
+		history = append(history, msg)
+	}
+
+	return Transcript{
+		Version:    TranscriptVersion,
+		ExportedAt: exportedAt.UTC(),
+		Conversation: TranscriptConversation{
+			ThreadID:     conv.ThreadID,
+			GuildID:      conv.GuildID,
+			ChannelID:    conv.ChannelID,
+			UserID:       conv.UserID,
+			Title:        conv.Title,
+			Model:        conv.Model,
+			SystemPrompt: conv.SystemPrompt,
+			Summary:      conv.Summary,
+			ParentID:     conv.ParentID,
+			TokenCount:   conv.TokenCount,
+			CreatedAt:    conv.CreatedAt.UTC(),
+			UpdatedAt:    conv.UpdatedAt.UTC(),
+		},
+		Messages: history,
+	}
+}
+
+// ExportFormats returns all supported export formats
+func ExportFormats() []string {
+	return []string{ExportMarkdown, ExportJSON, ExportHTML}
+}
+
+// Export renders a transcript in the given format
+// Returns the rendered data and the file extension to use for it
+func Export(t Transcript, format string) ([]byte, string, error) {
+	switch format {
+	case ExportMarkdown, "":
+		return []byte(RenderMarkdown(t)), "md", nil
+	case ExportJSON:
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode transcript: %w", err)
+		}
+		return data, "json", nil
+	case ExportHTML:
+		data, err := RenderHTML(t)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, "html", nil
+	default:
+		return nil, "", fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+// RenderMarkdown renders a transcript as a Markdown document
+func RenderMarkdown(t Transcript) string {
+	var sb strings.Builder
+	conv := t.Conversation
+
+	title := conv.Title
+	if title == "" {
+		title = "Conversation"
+	}
+	sb.WriteString(fmt.Sprintf("# %s\n\n", title))
+
+	sb.WriteString(fmt.Sprintf("- **Model:** `%s`\n", conv.Model))
+	sb.WriteString(fmt.Sprintf("- **Created:** %s\n", conv.CreatedAt.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("- **Updated:** %s\n", conv.UpdatedAt.Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("- **Tokens used:** %d\n", conv.TokenCount))
+	sb.WriteString(fmt.Sprintf("- **Exported:** %s\n\n", t.ExportedAt.Format(time.RFC3339)))
+
+	if conv.SystemPrompt != "" {
+		sb.WriteString("## System Prompt\n\n")
+		sb.WriteString(quote(conv.SystemPrompt))
+		sb.WriteString("\n\n")
+	}
+
+	if conv.Summary != "" {
+		sb.WriteString("## Summary of Earlier Messages\n\n")
+		sb.WriteString(quote(conv.Summary))
+		sb.WriteString("\n\n")
+	}
+
+	sb.WriteString("## Messages\n")
+	for _, msg := range t.Messages {
+		sb.WriteString(fmt.Sprintf("\n### %s", roleLabel(msg.Role)))
+		if msg.Tokens > 0 {
+			sb.WriteString(fmt.Sprintf(" (%d tokens)", msg.Tokens))
+		}
+		sb.WriteString("\n\n")
+		sb.WriteString(msg.Content)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// RenderHTML renders a transcript as a self-contained HTML page
+func RenderHTML(t Transcript) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := transcriptTemplate.Execute(&buf, t); err != nil {
+		return nil, fmt.Errorf("failed to render transcript: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// roleLabel returns a human-readable name for a message role
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	default:
+		return role
+	}
+}
+
+// quote renders text as a Markdown blockquote
+func quote(text string) string {
+	return "> " + strings.ReplaceAll(text, "\n", "\n> ")
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"role": roleLabel,
+	"date": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{with .Conversation.Title}}{{.}}{{else}}Conversation{{end}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+dl { display: grid; grid-template-columns: max-content 1fr; gap: .25rem 1rem; }
+dt { font-weight: 600; }
+.message { border-radius: .5rem; padding: .75rem 1rem; margin: 1rem 0; white-space: pre-wrap; }
+.user { background: #ddf4ff; }
+.assistant { background: #f6f8fa; }
+.system { background: #fff8c5; }
+.meta { font-size: .8rem; color: #59636e; margin-bottom: .25rem; white-space: normal; }
+</style>
+</head>
+<body>
+<h1>{{with .Conversation.Title}}{{.}}{{else}}Conversation{{end}}</h1>
+<dl>
+<dt>Model</dt><dd><code>{{.Conversation.Model}}</code></dd>
+<dt>Created</dt><dd>{{date .Conversation.CreatedAt}}</dd>
+<dt>Updated</dt><dd>{{date .Conversation.UpdatedAt}}</dd>
+<dt>Tokens used</dt><dd>{{.Conversation.TokenCount}}</dd>
+<dt>Exported</dt><dd>{{date .ExportedAt}}</dd>
+</dl>
+{{with .Conversation.SystemPrompt}}<h2>System Prompt</h2>
+<div class="message system">{{.}}</div>
+{{end}}{{with .Conversation.Summary}}<h2>Summary of Earlier Messages</h2>
+<div class="message system">{{.}}</div>
+{{end}}<h2>Messages</h2>
+{{range .Messages}}<div class="message {{.Role}}"><div class="meta">{{role .Role}}{{if .Tokens}} · {{.Tokens}} tokens{{end}}</div>{{.Content}}</div>
+{{end}}</body>
+</html>
+`))
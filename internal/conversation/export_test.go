@@ -0,0 +1,101 @@
+package conversation
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testTranscript() Transcript {
+	conv := Conversation{
+		ThreadID:     "thread123",
+		GuildID:      "guild456",
+		UserID:       "user789",
+		Model:        "openai/gpt-4o",
+		SystemPrompt: "You are helpful.",
+		Title:        "Go generics",
+		TokenCount:   120,
+		CreatedAt:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt:    time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC),
+	}
+	messages := []Message{
+		{Role: "system", Content: "You are helpful.", Tokens: 8},
+		{Role: "user", Content: "How do generics work?", Tokens: 10, MessageID: "100"},
+		{Role: "assistant", Content: "With <type parameters>.", Tokens: 20, MessageID: "101"},
+	}
+	return NewTranscript(conv, messages, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestNewTranscript(t *testing.T) {
+	transcript := testTranscript()
+
+	if transcript.Version != TranscriptVersion {
+		t.Errorf("Version = %d, want %d", transcript.Version, TranscriptVersion)
+	}
+	if len(transcript.Messages) != 2 {
+		t.Fatalf("Expected stored system prompt to be left out, got %d messages", len(transcript.Messages))
+	}
+	if transcript.Conversation.SystemPrompt != "You are helpful." {
+		t.Errorf("SystemPrompt = %q", transcript.Conversation.SystemPrompt)
+	}
+}
+
+func TestExport_Markdown(t *testing.T) {
+	data, ext, err := Export(testTranscript(), ExportMarkdown)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if ext != "md" {
+		t.Errorf("ext = %q, want md", ext)
+	}
+
+	out := string(data)
+	for _, want := range []string{"# Go generics", "`openai/gpt-4o`", "> You are helpful.", "### User (10 tokens)", "How do generics work?", "### Assistant"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown export missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestExport_JSON(t *testing.T) {
+	data, ext, err := Export(testTranscript(), ExportJSON)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if ext != "json" {
+		t.Errorf("ext = %q, want json", ext)
+	}
+
+	var got Transcript
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Exported JSON doesn't parse: %v", err)
+	}
+	if got.Version != TranscriptVersion || got.Conversation.Title != "Go generics" || len(got.Messages) != 2 {
+		t.Errorf("Round-tripped transcript = %+v", got)
+	}
+}
+
+func TestExport_HTML(t *testing.T) {
+	data, ext, err := Export(testTranscript(), ExportHTML)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if ext != "html" {
+		t.Errorf("ext = %q, want html", ext)
+	}
+
+	out := string(data)
+	if !strings.Contains(out, "<title>Go generics</title>") {
+		t.Error("HTML export missing title")
+	}
+	if strings.Contains(out, "<type parameters>") || !strings.Contains(out, "&lt;type parameters&gt;") {
+		t.Error("HTML export should escape message content")
+	}
+}
+
+func TestExport_UnknownFormat(t *testing.T) {
+	if _, _, err := Export(testTranscript(), "pdf"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
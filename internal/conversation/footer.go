@@ -0,0 +1,52 @@
+package conversation
+
+import "strings"
+
+const (
+	// footerPrefix starts the metadata line under a thread's first bot message
+	footerPrefix = "-# 🤖 "
+	// footerPromptSeparator separates the model from the system prompt name
+	footerPromptSeparator = " · 📝 "
+)
+
+// Footer is conversation metadata shown as subtext under a thread's first bot message
+// It lets a conversation be rebuilt from its thread if the stored state is lost.
+type Footer struct {
+	Model string
+	// Prompt is the name of a configured system prompt, empty for the default or a custom prompt
+	Prompt string
+}
+
+// String formats the footer as a Discord subtext line
+func (f Footer) String() string {
+	line := footerPrefix + "`" + f.Model + "`"
+	if f.Prompt != "" {
+		line += footerPromptSeparator + "`" + f.Prompt + "`"
+	}
+	return line
+}
+
+// ParseFooter extracts a footer from the last line of a message
+// Returns the content without the footer, or false if the message has no footer.
+func ParseFooter(content string) (Footer, string, bool) {
+	body, line := "", content
+	if idx := strings.LastIndex(content, "\n"); idx >= 0 {
+		body, line = content[:idx], content[idx+1:]
+	}
+
+	rest, ok := strings.CutPrefix(line, footerPrefix)
+	if !ok {
+		return Footer{}, content, false
+	}
+
+	model, prompt, _ := strings.Cut(rest, footerPromptSeparator)
+	footer := Footer{
+		Model:  strings.Trim(model, "`"),
+		Prompt: strings.Trim(prompt, "`"),
+	}
+	if footer.Model == "" {
+		return Footer{}, content, false
+	}
+
+	return footer, body, true
+}
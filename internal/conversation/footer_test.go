@@ -0,0 +1,44 @@
+package conversation
+
+import "testing"
+
+func TestFooter_RoundTrip(t *testing.T) {
+	tests := []Footer{
+		{Model: "openai/gpt-4o"},
+		{Model: "anthropic/claude-3-5-sonnet", Prompt: "coder"},
+	}
+
+	for _, footer := range tests {
+		content := "Hello there\n\nSecond paragraph\n" + footer.String()
+		got, body, ok := ParseFooter(content)
+		if !ok {
+			t.Fatalf("ParseFooter(%q) found no footer", content)
+		}
+		if got != footer {
+			t.Errorf("ParseFooter() = %+v, want %+v", got, footer)
+		}
+		if body != "Hello there\n\nSecond paragraph" {
+			t.Errorf("ParseFooter() body = %q", body)
+		}
+	}
+}
+
+func TestParseFooter_Missing(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"Just a reply",
+		"-# some other subtext",
+		"Reply\n-# 🤖 ``",
+	} {
+		if _, body, ok := ParseFooter(content); ok || body != content {
+			t.Errorf("ParseFooter(%q) = %q, %v, want no footer", content, body, ok)
+		}
+	}
+}
+
+func TestParseFooter_OnlyLine(t *testing.T) {
+	footer, body, ok := ParseFooter(Footer{Model: "openai/gpt-4o"}.String())
+	if !ok || footer.Model != "openai/gpt-4o" || body != "" {
+		t.Errorf("ParseFooter() = %+v, %q, %v", footer, body, ok)
+	}
+}
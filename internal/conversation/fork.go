@@ -0,0 +1,23 @@
+package conversation
+
+import "errors"
+
+// ErrMessageNotInHistory is returned when a Discord message isn't part of a conversation's stored history
+var ErrMessageNotInHistory = errors.New("message is not part of the conversation history")
+
+// ForkHistory returns a copy of the history up to and including the message with the given Discord ID
+// Copied messages lose their Discord IDs, since those messages live in the parent thread
+func ForkHistory(messages []Message, messageID string) ([]Message, error) {
+	end := FindMessage(messages, messageID)
+	if end < 0 {
+		return nil, ErrMessageNotInHistory
+	}
+
+	history := make([]Message, end+1)
+	copy(history, messages[:end+1])
+	for i := range history {
+		history[i].SetDiscordIDs(nil)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,73 @@
+package conversation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestForkHistory(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "System prompt"},
+		{Role: "user", Content: "Question", MessageID: "100"},
+		{Role: "assistant", Content: "Answer", MessageID: "101"},
+		{Role: "user", Content: "Follow-up", MessageID: "102"},
+		{Role: "assistant", Content: "Another answer", MessageID: "103"},
+	}
+
+	history, err := ForkHistory(messages, "101")
+	if err != nil {
+		t.Fatalf("ForkHistory() error = %v", err)
+	}
+
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(history))
+	}
+	if history[2].Content != "Answer" {
+		t.Errorf("Last message = %q, want Answer", history[2].Content)
+	}
+	for _, msg := range history {
+		if msg.MessageID != "" {
+			t.Errorf("Forked message kept Discord ID %q", msg.MessageID)
+		}
+	}
+
+	// The parent's history must be untouched
+	if messages[1].MessageID != "100" {
+		t.Error("ForkHistory modified the original messages")
+	}
+}
+
+func TestForkHistory_SplitReply(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "Question", MessageID: "100"},
+		{Role: "assistant", Content: "A long answer", MessageID: "103", Parts: []string{"101", "102"}},
+	}
+
+	// Forking from any message of a split reply includes all of it
+	history, err := ForkHistory(messages, "101")
+	if err != nil {
+		t.Fatalf("ForkHistory() error = %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(history))
+	}
+	if ids := history[1].DiscordIDs(); len(ids) != 0 {
+		t.Errorf("Forked reply kept Discord IDs %v", ids)
+	}
+	if len(messages[1].Parts) != 2 {
+		t.Error("ForkHistory modified the original messages")
+	}
+}
+
+func TestForkHistory_NotFound(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "System prompt"},
+		{Role: "user", Content: "Question", MessageID: "100"},
+	}
+
+	for _, id := range []string{"999", ""} {
+		if _, err := ForkHistory(messages, id); !errors.Is(err, ErrMessageNotInHistory) {
+			t.Errorf("ForkHistory(%q) error = %v, want ErrMessageNotInHistory", id, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// importMessage is a message as found in imported transcripts
+// Content is either a string or an OpenAI-style array of content parts
+type importMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// importDocument covers both exported transcripts and OpenAI-style request bodies
+type importDocument struct {
+	Version      int                    `json:"version"`
+	Model        string                 `json:"model"`
+	Conversation TranscriptConversation `json:"conversation"`
+	Messages     []importMessage        `json:"messages"`
+}
+
+// ParseTranscript parses an exported JSON transcript, an OpenAI-style messages array,
+// or an object with a "messages" array (such as a chat completion request body).
+// Leading system messages become the system prompt; token counts and Discord IDs are dropped
+// so the caller can re-count them for the target model.
+func ParseTranscript(data []byte) (*Transcript, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("transcript is empty")
+	}
+
+	var doc importDocument
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &doc.Messages); err != nil {
+			return nil, fmt.Errorf("invalid messages array: %w", err)
+		}
+	} else {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid transcript: %w", err)
+		}
+		if doc.Version > TranscriptVersion {
+			return nil, fmt.Errorf("transcript version %d is newer than supported version %d", doc.Version, TranscriptVersion)
+		}
+	}
+
+	if len(doc.Messages) == 0 {
+		return nil, fmt.Errorf("transcript has no messages")
+	}
+
+	t := &Transcript{
+		Version: TranscriptVersion,
+		Conversation: TranscriptConversation{
+			Title:        doc.Conversation.Title,
+			Model:        doc.Conversation.Model,
+			SystemPrompt: doc.Conversation.SystemPrompt,
+			Summary:      doc.Conversation.Summary,
+		},
+	}
+	if t.Conversation.Model == "" {
+		t.Conversation.Model = doc.Model
+	}
+
+	var systemPrompts []string
+	for i, raw := range doc.Messages {
+		content, err := importContent(raw.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+
+		switch raw.Role {
+		case "system", "developer":
+			// Only a leading system prompt is supported
+			if len(t.Messages) > 0 {
+				return nil, fmt.Errorf("messages[%d]: system messages are only allowed at the start", i)
+			}
+			systemPrompts = append(systemPrompts, content)
+		case "user", "assistant":
+			if strings.TrimSpace(content) == "" {
+				return nil, fmt.Errorf("messages[%d]: content is empty", i)
+			}
+			t.Messages = append(t.Messages, Message{Role: raw.Role, Content: content})
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, raw.Role)
+		}
+	}
+
+	if len(t.Messages) == 0 {
+		return nil, fmt.Errorf("transcript has no user or assistant messages")
+	}
+
+	if len(systemPrompts) > 0 {
+		t.Conversation.SystemPrompt = strings.Join(systemPrompts, "\n\n")
+	}
+
+	return t, nil
+}
+
+// importContent extracts the text of a message's content
+func importContent(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+
+	// OpenAI content parts: only text parts can be imported
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("content must be a string or an array of content parts")
+	}
+
+	var texts []string
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
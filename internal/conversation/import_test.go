@@ -0,0 +1,87 @@
+package conversation
+
+import "testing"
+
+func TestParseTranscript_ExportRoundTrip(t *testing.T) {
+	data, _, err := Export(testTranscript(), ExportJSON)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	got, err := ParseTranscript(data)
+	if err != nil {
+		t.Fatalf("ParseTranscript() error = %v", err)
+	}
+
+	if got.Conversation.Title != "Go generics" || got.Conversation.Model != "openai/gpt-4o" {
+		t.Errorf("Conversation = %+v", got.Conversation)
+	}
+	if got.Conversation.SystemPrompt != "You are helpful." {
+		t.Errorf("SystemPrompt = %q", got.Conversation.SystemPrompt)
+	}
+	if len(got.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(got.Messages))
+	}
+	for _, msg := range got.Messages {
+		if msg.Tokens != 0 || msg.MessageID != "" {
+			t.Errorf("Imported message kept tokens or Discord ID: %+v", msg)
+		}
+	}
+}
+
+func TestParseTranscript_OpenAIMessages(t *testing.T) {
+	data := []byte(`[
+		{"role": "system", "content": "Be terse."},
+		{"role": "user", "content": [{"type": "text", "text": "Hello"}, {"type": "text", "text": "there"}]},
+		{"role": "assistant", "content": "Hi."}
+	]`)
+
+	got, err := ParseTranscript(data)
+	if err != nil {
+		t.Fatalf("ParseTranscript() error = %v", err)
+	}
+
+	if got.Conversation.SystemPrompt != "Be terse." {
+		t.Errorf("SystemPrompt = %q, want 'Be terse.'", got.Conversation.SystemPrompt)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Content != "Hello\nthere" {
+		t.Errorf("Messages = %+v", got.Messages)
+	}
+}
+
+func TestParseTranscript_RequestBody(t *testing.T) {
+	data := []byte(`{"model": "openai/gpt-4o-mini", "messages": [{"role": "user", "content": "Hi"}]}`)
+
+	got, err := ParseTranscript(data)
+	if err != nil {
+		t.Fatalf("ParseTranscript() error = %v", err)
+	}
+	if got.Conversation.Model != "openai/gpt-4o-mini" {
+		t.Errorf("Model = %q, want openai/gpt-4o-mini", got.Conversation.Model)
+	}
+}
+
+func TestParseTranscript_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"not json", "hello"},
+		{"no messages", `{"messages": []}`},
+		{"bad role", `[{"role": "tool", "content": "x"}]`},
+		{"late system message", `[{"role": "user", "content": "x"}, {"role": "system", "content": "y"}]`},
+		{"empty content", `[{"role": "user", "content": "  "}]`},
+		{"image part", `[{"role": "user", "content": [{"type": "image_url"}]}]`},
+		{"only system", `[{"role": "system", "content": "x"}]`},
+		{"future version", `{"version": 99, "messages": [{"role": "user", "content": "x"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseTranscript([]byte(tt.data)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
@@ -2,17 +2,35 @@ package conversation
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+	"github.com/s33g/discord-prompter/internal/search"
 	"github.com/s33g/discord-prompter/internal/storage"
 )
 
+// Settings are the storage limits applied to a guild's conversations
+type Settings struct {
+	TTL         time.Duration
+	MaxMessages int
+}
+
+// SettingsFunc resolves a guild's settings; zero values fall back to the manager's defaults
+type SettingsFunc func(guildID string) Settings
+
 // Manager handles conversation storage and retrieval
 type Manager struct {
-	client      *storage.Client
-	ttl         time.Duration
-	maxMessages int
+	client        *storage.Client
+	ttl           time.Duration
+	maxMessages   int
+	guildSettings SettingsFunc
+	archive       storage.Archive
+	writeThrough  bool
+	search        search.Index
 }
 
 // NewManager creates a new conversation manager
@@ -24,12 +42,35 @@ func NewManager(client *storage.Client, ttl time.Duration, maxMessages int) *Man
 	}
 }
 
+// WithArchive attaches a durable archive to the manager
+// With writeThrough set, every change is copied to the archive as it is made;
+// otherwise conversations are only archived by Archive and ArchiveExpiring.
+func (m *Manager) WithArchive(archive storage.Archive, writeThrough bool) *Manager {
+	m.archive = archive
+	m.writeThrough = writeThrough
+	return m
+}
+
+// WithSettings resolves TTL and history limits per guild instead of using the defaults for all guilds
+// The function is called on every write, so it can read configuration that is reloaded at runtime.
+func (m *Manager) WithSettings(fn SettingsFunc) *Manager {
+	m.guildSettings = fn
+	return m
+}
+
+// WithSearch attaches a full-text index that new and edited messages are added to
+func (m *Manager) WithSearch(index search.Index) *Manager {
+	m.search = index
+	return m
+}
+
 // Create creates a new conversation
 func (m *Manager) Create(ctx context.Context, conv Conversation) error {
 	now := time.Now()
 	conv.CreatedAt = now
 	conv.UpdatedAt = now
 
+	settings := m.settings(conv.GuildID)
 	key := m.client.Keys().Conversation(conv.GuildID, conv.ThreadID)
 
 	// Store conversation metadata
@@ -38,17 +79,22 @@ func (m *Manager) Create(ctx context.Context, conv Conversation) error {
 	}
 
 	// Set TTL
-	if err := m.client.Redis().Expire(ctx, key, m.ttl).Err(); err != nil {
+	if err := m.client.Redis().Expire(ctx, key, settings.TTL).Err(); err != nil {
 		return fmt.Errorf("failed to set conversation TTL: %w", err)
 	}
 
 	// Create empty message list with TTL
 	msgKey := m.client.Keys().Messages(conv.GuildID, conv.ThreadID)
-	if err := m.client.Redis().Expire(ctx, msgKey, m.ttl).Err(); err != nil {
+	if err := m.client.Redis().Expire(ctx, msgKey, settings.TTL).Err(); err != nil {
 		return fmt.Errorf("failed to set messages TTL: %w", err)
 	}
 
-	return nil
+	// Index by guild and owner for /conversations
+	if err := m.index(ctx, conv); err != nil {
+		return err
+	}
+
+	return m.writeArchive(ctx, conv.GuildID, conv.ThreadID)
 }
 
 // Get retrieves a conversation by thread ID
@@ -82,7 +128,11 @@ func (m *Manager) Update(ctx context.Context, conv Conversation) error {
 		return fmt.Errorf("failed to update conversation: %w", err)
 	}
 
-	return nil
+	if err := m.index(ctx, conv); err != nil {
+		return err
+	}
+
+	return m.writeArchive(ctx, conv.GuildID, conv.ThreadID)
 }
 
 // Delete deletes a conversation and its messages
@@ -90,21 +140,52 @@ func (m *Manager) Delete(ctx context.Context, guildID, threadID string) error {
 	convKey := m.client.Keys().Conversation(guildID, threadID)
 	msgKey := m.client.Keys().Messages(guildID, threadID)
 
+	forksKey := m.client.Keys().Forks(guildID, threadID)
+
+	// Look up the owner and parent so the conversation can be dropped from their indexes
+	fields, err := m.client.Redis().HMGet(ctx, convKey, "user_id", "parent_id").Result()
+	if err != nil {
+		return fmt.Errorf("failed to get conversation owner: %w", err)
+	}
+	userID, _ := fields[0].(string)
+	parentID, _ := fields[1].(string)
+
 	pipe := m.client.Redis().Pipeline()
 	pipe.Del(ctx, convKey)
 	pipe.Del(ctx, msgKey)
+	pipe.Del(ctx, forksKey)
+	pipe.ZRem(ctx, m.client.Keys().GuildConversations(guildID), threadID)
+	if userID != "" {
+		pipe.ZRem(ctx, m.client.Keys().UserConversations(guildID, userID), threadID)
+	}
+	if parentID != "" {
+		pipe.SRem(ctx, m.client.Keys().Forks(guildID, parentID), threadID)
+	}
 
-	_, err := pipe.Exec(ctx)
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to delete conversation: %w", err)
 	}
 
+	if m.search != nil {
+		if err := m.search.RemoveThread(ctx, guildID, threadID); err != nil {
+			return err
+		}
+	}
+
+	// Deleted conversations shouldn't come back from the archive
+	if m.archive != nil {
+		if err := m.archive.Delete(ctx, guildID, threadID); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // AddMessage adds a message to the conversation history
 func (m *Manager) AddMessage(ctx context.Context, guildID, threadID string, msg Message) error {
 	msgKey := m.client.Keys().Messages(guildID, threadID)
+	settings := m.settings(guildID)
 
 	// Marshal message
 	data, err := MarshalMessage(msg)
@@ -118,16 +199,86 @@ func (m *Manager) AddMessage(ctx context.Context, guildID, threadID string, msg
 	pipe.RPush(ctx, msgKey, data)
 
 	// Trim to max size
-	pipe.LTrim(ctx, msgKey, -int64(m.maxMessages), -1)
+	pipe.LTrim(ctx, msgKey, -int64(settings.MaxMessages), -1)
 
 	// Update TTL
-	pipe.Expire(ctx, msgKey, m.ttl)
+	pipe.Expire(ctx, msgKey, settings.TTL)
 
 	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to add message: %w", err)
 	}
 
-	return nil
+	if err := m.touch(ctx, guildID, threadID); err != nil {
+		return err
+	}
+
+	if err := m.indexMessages(ctx, guildID, threadID, []Message{msg}, false); err != nil {
+		return err
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// ReplaceMessages overwrites a conversation's history, e.g. after an edit or deletion
+func (m *Manager) ReplaceMessages(ctx context.Context, guildID, threadID string, messages []Message) error {
+	msgKey := m.client.Keys().Messages(guildID, threadID)
+	settings := m.settings(guildID)
+
+	values, err := marshalMessages(messages)
+	if err != nil {
+		return err
+	}
+
+	pipe := m.client.Redis().TxPipeline()
+	pipe.Del(ctx, msgKey)
+	if len(values) > 0 {
+		pipe.RPush(ctx, msgKey, values...)
+		pipe.LTrim(ctx, msgKey, -int64(settings.MaxMessages), -1)
+		pipe.Expire(ctx, msgKey, settings.TTL)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to replace messages: %w", err)
+	}
+
+	if err := m.indexMessages(ctx, guildID, threadID, messages, true); err != nil {
+		return err
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// SetMessage overwrites one message of a conversation's history in place, e.g. after a regeneration
+// Negative indexes count from the end of the history, so -1 is the latest message.
+func (m *Manager) SetMessage(ctx context.Context, guildID, threadID string, index int, msg Message) error {
+	msgKey := m.client.Keys().Messages(guildID, threadID)
+	settings := m.settings(guildID)
+
+	data, err := MarshalMessage(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	pipe := m.client.Redis().TxPipeline()
+	pipe.LSet(ctx, msgKey, int64(index), data)
+	pipe.Expire(ctx, msgKey, settings.TTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set message: %w", err)
+	}
+
+	// The search index has no per-message entries, so reindex the thread
+	if m.search != nil {
+		messages, err := m.GetMessages(ctx, guildID, threadID)
+		if err != nil {
+			return err
+		}
+		if err := m.indexMessages(ctx, guildID, threadID, messages, true); err != nil {
+			return err
+		}
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
 }
 
 // GetMessages retrieves all messages in a conversation
@@ -160,7 +311,96 @@ func (m *Manager) ClearMessages(ctx context.Context, guildID, threadID string) e
 		return fmt.Errorf("failed to clear messages: %w", err)
 	}
 
-	return nil
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// Compact replaces the oldest messages with a rolling summary
+// The first count messages after the stored system prompt are removed from the history and the summary is
+// stored on the conversation. The system prompt stays at the start of the history.
+func (m *Manager) Compact(ctx context.Context, guildID, threadID string, count int, summary string) error {
+	key := m.client.Keys().Conversation(guildID, threadID)
+	msgKey := m.client.Keys().Messages(guildID, threadID)
+
+	// Keep a leading system prompt, which isn't part of the compacted history
+	var head []string
+	first, err := m.client.Redis().LIndex(ctx, msgKey, 0).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return fmt.Errorf("failed to get messages: %w", err)
+	}
+	if msg, err := UnmarshalMessage(first); err == nil && msg.Role == "system" {
+		head = append(head, first)
+	}
+
+	pipe := m.client.Redis().TxPipeline()
+	pipe.HSet(ctx, key, "summary", summary, "updated_at", time.Now().Unix())
+	pipe.LTrim(ctx, msgKey, int64(len(head)+count), -1)
+	if len(head) > 0 {
+		pipe.LPush(ctx, msgKey, head[0])
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to compact messages: %w", err)
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// Fork creates a conversation from a copy of another conversation's history
+// The child is recorded in its parent's fork set so forks can be listed later
+func (m *Manager) Fork(ctx context.Context, child Conversation, messages []Message) error {
+	if child.ParentID == "" {
+		return fmt.Errorf("fork has no parent conversation")
+	}
+
+	now := time.Now()
+	child.CreatedAt = now
+	child.UpdatedAt = now
+
+	key := m.client.Keys().Conversation(child.GuildID, child.ThreadID)
+	msgKey := m.client.Keys().Messages(child.GuildID, child.ThreadID)
+	forksKey := m.client.Keys().Forks(child.GuildID, child.ParentID)
+	settings := m.settings(child.GuildID)
+
+	pipe := m.client.Redis().TxPipeline()
+	pipe.HSet(ctx, key, child.ToMap())
+	pipe.Expire(ctx, key, settings.TTL)
+
+	if len(messages) > 0 {
+		values, err := marshalMessages(messages)
+		if err != nil {
+			return err
+		}
+		pipe.RPush(ctx, msgKey, values...)
+		pipe.LTrim(ctx, msgKey, -int64(settings.MaxMessages), -1)
+		pipe.Expire(ctx, msgKey, settings.TTL)
+	}
+
+	pipe.SAdd(ctx, forksKey, child.ThreadID)
+	pipe.Expire(ctx, forksKey, settings.TTL)
+	m.indexPipe(ctx, pipe, child)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to fork conversation: %w", err)
+	}
+
+	if err := m.indexMessages(ctx, child.GuildID, child.ThreadID, messages, false); err != nil {
+		return err
+	}
+
+	return m.writeArchive(ctx, child.GuildID, child.ThreadID)
+}
+
+// GetForks returns the thread IDs of conversations forked from a conversation
+func (m *Manager) GetForks(ctx context.Context, guildID, threadID string) ([]string, error) {
+	forksKey := m.client.Keys().Forks(guildID, threadID)
+
+	forks, err := m.client.Redis().SMembers(ctx, forksKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forks: %w", err)
+	}
+
+	sort.Strings(forks)
+	return forks, nil
 }
 
 // UpdateModel changes the model for a conversation
@@ -175,7 +415,7 @@ func (m *Manager) UpdateModel(ctx context.Context, guildID, threadID, model stri
 		return fmt.Errorf("failed to update model: %w", err)
 	}
 
-	return nil
+	return m.writeArchive(ctx, guildID, threadID)
 }
 
 // UpdateSystemPrompt changes the system prompt for a conversation
@@ -190,7 +430,37 @@ func (m *Manager) UpdateSystemPrompt(ctx context.Context, guildID, threadID, pro
 		return fmt.Errorf("failed to update system prompt: %w", err)
 	}
 
-	return nil
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// UpdateStrategy changes the context window strategy for a conversation
+func (m *Manager) UpdateStrategy(ctx context.Context, guildID, threadID, strategy string) error {
+	key := m.client.Keys().Conversation(guildID, threadID)
+
+	pipe := m.client.Redis().Pipeline()
+	pipe.HSet(ctx, key, "strategy", strategy)
+	pipe.HSet(ctx, key, "updated_at", time.Now().Unix())
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update strategy: %w", err)
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// UpdatePosting changes who may post in a conversation
+func (m *Manager) UpdatePosting(ctx context.Context, guildID, threadID, policy string, posters []string) error {
+	key := m.client.Keys().Conversation(guildID, threadID)
+
+	pipe := m.client.Redis().Pipeline()
+	pipe.HSet(ctx, key, "posting", policy, "posters", strings.Join(posters, ","))
+	pipe.HSet(ctx, key, "updated_at", time.Now().Unix())
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update posting policy: %w", err)
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
 }
 
 // UpdateTitle updates the conversation title
@@ -205,16 +475,340 @@ func (m *Manager) UpdateTitle(ctx context.Context, guildID, threadID, title stri
 		return fmt.Errorf("failed to update title: %w", err)
 	}
 
-	return nil
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// MarkRetitled records that a conversation's title has been re-checked, with the title it ended up with
+func (m *Manager) MarkRetitled(ctx context.Context, guildID, threadID, title string) error {
+	key := m.client.Keys().Conversation(guildID, threadID)
+
+	pipe := m.client.Redis().Pipeline()
+	pipe.HSet(ctx, key, "title", title, "retitled", true)
+	pipe.HSet(ctx, key, "updated_at", time.Now().Unix())
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update title: %w", err)
+	}
+
+	return m.writeArchive(ctx, guildID, threadID)
 }
 
 // IncrementTokenCount adds tokens to the conversation's total
+// Turns record their usage with it rather than Update, so they don't write back settings changed meanwhile.
 func (m *Manager) IncrementTokenCount(ctx context.Context, guildID, threadID string, tokens int) error {
 	key := m.client.Keys().Conversation(guildID, threadID)
 
-	if err := m.client.Redis().HIncrBy(ctx, key, "token_count", int64(tokens)).Err(); err != nil {
+	pipe := m.client.Redis().Pipeline()
+	pipe.HIncrBy(ctx, key, "token_count", int64(tokens))
+	pipe.HSet(ctx, key, "updated_at", time.Now().Unix())
+
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to increment token count: %w", err)
 	}
 
+	return m.writeArchive(ctx, guildID, threadID)
+}
+
+// Archive copies a conversation and its messages to the archive
+func (m *Manager) Archive(ctx context.Context, guildID, threadID string) error {
+	if m.archive == nil {
+		return fmt.Errorf("archive is not configured")
+	}
+
+	key := m.client.Keys().Conversation(guildID, threadID)
+	msgKey := m.client.Keys().Messages(guildID, threadID)
+
+	pipe := m.client.Redis().Pipeline()
+	fieldsCmd := pipe.HGetAll(ctx, key)
+	messagesCmd := pipe.LRange(ctx, msgKey, 0, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to read conversation for archive: %w", err)
+	}
+
+	fields := fieldsCmd.Val()
+	if len(fields) == 0 {
+		return fmt.Errorf("conversation not found")
+	}
+
+	return m.archive.Save(ctx, storage.ArchiveRecord{
+		GuildID:    guildID,
+		ThreadID:   threadID,
+		Fields:     fields,
+		Messages:   messagesCmd.Val(),
+		ArchivedAt: time.Now(),
+	})
+}
+
+// ArchiveExpiring archives every conversation whose Redis keys expire within the given window
+// Returns the number of conversations archived
+func (m *Manager) ArchiveExpiring(ctx context.Context, within time.Duration) (int, error) {
+	if m.archive == nil {
+		return 0, fmt.Errorf("archive is not configured")
+	}
+
+	archived := 0
+	iter := m.client.Redis().Scan(ctx, 0, m.client.Keys().ConversationPattern(), 100).Iterator()
+	for iter.Next(ctx) {
+		guildID, threadID, ok := m.client.Keys().ParseConversation(iter.Val())
+		if !ok {
+			continue
+		}
+
+		ttl, err := m.client.Redis().TTL(ctx, iter.Val()).Result()
+		if err != nil {
+			return archived, fmt.Errorf("failed to get conversation TTL: %w", err)
+		}
+		// Negative TTLs mean the key has no expiry or is already gone
+		if ttl < 0 || ttl > within {
+			continue
+		}
+
+		if err := m.Archive(ctx, guildID, threadID); err != nil {
+			return archived, err
+		}
+		archived++
+	}
+	if err := iter.Err(); err != nil {
+		return archived, fmt.Errorf("failed to scan conversations: %w", err)
+	}
+
+	return archived, nil
+}
+
+// Rehydrate restores an archived conversation into Redis with a fresh TTL
+// Returns storage.ErrNotArchived if the conversation was never archived
+func (m *Manager) Rehydrate(ctx context.Context, guildID, threadID string) (*Conversation, error) {
+	if m.archive == nil {
+		return nil, storage.ErrNotArchived
+	}
+
+	record, err := m.archive.Load(ctx, guildID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conv Conversation
+	if err := conv.FromMap(threadID, record.Fields); err != nil {
+		return nil, err
+	}
+
+	key := m.client.Keys().Conversation(guildID, threadID)
+	msgKey := m.client.Keys().Messages(guildID, threadID)
+	settings := m.settings(guildID)
+
+	fields := make(map[string]interface{}, len(record.Fields))
+	for field, value := range record.Fields {
+		fields[field] = value
+	}
+
+	pipe := m.client.Redis().TxPipeline()
+	pipe.HSet(ctx, key, fields)
+	pipe.Expire(ctx, key, settings.TTL)
+	pipe.Del(ctx, msgKey)
+	if len(record.Messages) > 0 {
+		values := make([]interface{}, len(record.Messages))
+		for i, data := range record.Messages {
+			values[i] = data
+		}
+		pipe.RPush(ctx, msgKey, values...)
+		pipe.LTrim(ctx, msgKey, -int64(settings.MaxMessages), -1)
+		pipe.Expire(ctx, msgKey, settings.TTL)
+	}
+
+	m.indexPipe(ctx, pipe, conv)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to rehydrate conversation: %w", err)
+	}
+
+	// The thread's search entries may have expired with it
+	messages, err := m.GetMessages(ctx, guildID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.indexMessages(ctx, guildID, threadID, messages, true); err != nil {
+		return nil, err
+	}
+
+	return &conv, nil
+}
+
+// List returns a page of a guild's conversations, most recently active first
+// With a user ID only that user's conversations are listed. Also returns the total number of indexed conversations.
+// Index entries whose conversation has expired (and isn't archived) are pruned as they are found.
+func (m *Manager) List(ctx context.Context, guildID, userID string, offset, limit int) ([]Conversation, int, error) {
+	indexKey := m.client.Keys().GuildConversations(guildID)
+	if userID != "" {
+		indexKey = m.client.Keys().UserConversations(guildID, userID)
+	}
+
+	total, err := m.client.Redis().ZCard(ctx, indexKey).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count conversations: %w", err)
+	}
+
+	threadIDs, err := m.client.Redis().ZRevRange(ctx, indexKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	conversations := make([]Conversation, 0, len(threadIDs))
+	var stale []interface{}
+	for _, threadID := range threadIDs {
+		conv, err := m.Get(ctx, guildID, threadID)
+		if err != nil {
+			conv, err = m.archived(ctx, guildID, threadID)
+		}
+		if err != nil {
+			stale = append(stale, threadID)
+			continue
+		}
+		conversations = append(conversations, *conv)
+	}
+
+	if len(stale) > 0 {
+		if err := m.client.Redis().ZRem(ctx, indexKey, stale...).Err(); err != nil {
+			return nil, 0, fmt.Errorf("failed to prune conversation index: %w", err)
+		}
+		total -= int64(len(stale))
+	}
+
+	return conversations, int(total), nil
+}
+
+// archived returns the metadata of an archived conversation
+func (m *Manager) archived(ctx context.Context, guildID, threadID string) (*Conversation, error) {
+	if m.archive == nil {
+		return nil, storage.ErrNotArchived
+	}
+
+	record, err := m.archive.Load(ctx, guildID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conv Conversation
+	if err := conv.FromMap(threadID, record.Fields); err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// index records a conversation in its guild and owner indexes
+func (m *Manager) index(ctx context.Context, conv Conversation) error {
+	pipe := m.client.Redis().Pipeline()
+	m.indexPipe(ctx, pipe, conv)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to index conversation: %w", err)
+	}
+	return nil
+}
+
+// indexPipe queues the index updates for a conversation on a pipeline
+func (m *Manager) indexPipe(ctx context.Context, pipe redis.Pipeliner, conv Conversation) {
+	entry := redis.Z{Score: float64(conv.UpdatedAt.Unix()), Member: conv.ThreadID}
+
+	pipe.ZAdd(ctx, m.client.Keys().GuildConversations(conv.GuildID), entry)
+	if conv.UserID != "" {
+		pipe.ZAdd(ctx, m.client.Keys().UserConversations(conv.GuildID, conv.UserID), entry)
+	}
+}
+
+// touch marks a conversation as active now, moving it to the top of its indexes
+func (m *Manager) touch(ctx context.Context, guildID, threadID string) error {
+	key := m.client.Keys().Conversation(guildID, threadID)
+
+	userID, err := m.client.Redis().HGet(ctx, key, "user_id").Result()
+	if errors.Is(err, redis.Nil) {
+		// Messages for an unknown conversation aren't indexed
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get conversation owner: %w", err)
+	}
+
+	now := time.Now()
+	pipe := m.client.Redis().Pipeline()
+	pipe.HSet(ctx, key, "updated_at", now.Unix())
+	m.indexPipe(ctx, pipe, Conversation{GuildID: guildID, ThreadID: threadID, UserID: userID, UpdatedAt: now})
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update conversation activity: %w", err)
+	}
+	return nil
+}
+
+// indexMessages adds messages to the search index, replacing the thread's entries if replace is set
+// System prompts aren't indexed since they aren't part of the visible conversation
+func (m *Manager) indexMessages(ctx context.Context, guildID, threadID string, messages []Message, replace bool) error {
+	if m.search == nil {
+		return nil
+	}
+
+	if replace {
+		if err := m.search.RemoveThread(ctx, guildID, threadID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
+		doc := search.Document{
+			GuildID:  guildID,
+			ThreadID: threadID,
+			Role:     msg.Role,
+			Content:  msg.Content,
+			Time:     msg.Time(),
+		}
+		if doc.Time.IsZero() {
+			doc.Time = now
+		}
+		if err := m.search.Add(ctx, doc); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// writeArchive copies a conversation to the archive after a change in write-through mode
+func (m *Manager) writeArchive(ctx context.Context, guildID, threadID string) error {
+	if m.archive == nil || !m.writeThrough {
+		return nil
+	}
+	if err := m.Archive(ctx, guildID, threadID); err != nil {
+		return fmt.Errorf("failed to write through to archive: %w", err)
+	}
+	return nil
+}
+
+// marshalMessages converts messages to JSON values for RPUSH
+func marshalMessages(messages []Message) ([]interface{}, error) {
+	values := make([]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		data, err := MarshalMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal message: %w", err)
+		}
+		values = append(values, data)
+	}
+	return values, nil
+}
+
+// settings returns the storage limits for a guild's conversations
+func (m *Manager) settings(guildID string) Settings {
+	var settings Settings
+	if m.guildSettings != nil {
+		settings = m.guildSettings(guildID)
+	}
+	if settings.TTL <= 0 {
+		settings.TTL = m.ttl
+	}
+	if settings.MaxMessages <= 0 {
+		settings.MaxMessages = m.maxMessages
+	}
+	return settings
+}
@@ -2,9 +2,11 @@ package conversation
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/s33g/discord-prompter/internal/config"
 	"github.com/s33g/discord-prompter/internal/storage"
 )
@@ -228,3 +230,398 @@ func TestManager_MessageTrimming(t *testing.T) {
 		}
 	}
 }
+
+func TestManager_GuildSettings(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	// Guild "small" keeps fewer messages for less time; other guilds use the defaults
+	mgr := NewManager(client, time.Hour, 50).WithSettings(func(guildID string) Settings {
+		if guildID == "small" {
+			return Settings{TTL: time.Minute, MaxMessages: 2}
+		}
+		return Settings{}
+	})
+	ctx := context.Background()
+
+	for _, guildID := range []string{"small", "large"} {
+		mgr.Create(ctx, Conversation{ThreadID: "thread123", GuildID: guildID, Model: "test/model"})
+		for i := 0; i < 5; i++ {
+			mgr.AddMessage(ctx, guildID, "thread123", Message{Role: "user", Content: string(rune('A' + i))})
+		}
+	}
+
+	small, _ := mgr.GetMessages(ctx, "small", "thread123")
+	if len(small) != 2 {
+		t.Errorf("Small guild has %d messages, want 2", len(small))
+	}
+	large, _ := mgr.GetMessages(ctx, "large", "thread123")
+	if len(large) != 5 {
+		t.Errorf("Large guild has %d messages, want 5", len(large))
+	}
+
+	ttl := client.Redis().TTL(ctx, client.Keys().Conversation("small", "thread123")).Val()
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Small guild conversation TTL = %v, want at most a minute", ttl)
+	}
+	ttl = client.Redis().TTL(ctx, client.Keys().Conversation("large", "thread123")).Val()
+	if ttl <= time.Minute {
+		t.Errorf("Large guild conversation TTL = %v, want the default hour", ttl)
+	}
+}
+
+func TestManager_Compact(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	mgr := NewManager(client, time.Hour, 50)
+	ctx := context.Background()
+
+	// Create conversation with messages
+	conv := Conversation{
+		ThreadID: "thread123",
+		GuildID:  "guild456",
+		Model:    "test/model",
+	}
+	mgr.Create(ctx, conv)
+	for _, content := range []string{"A", "B", "C", "D"} {
+		mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "user", Content: content})
+	}
+
+	// Compact the two oldest messages
+	if err := mgr.Compact(ctx, "guild456", "thread123", 2, "A and B happened"); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	messages, _ := mgr.GetMessages(ctx, "guild456", "thread123")
+	if len(messages) != 2 || messages[0].Content != "C" || messages[1].Content != "D" {
+		t.Errorf("Messages after compact = %+v, want C, D", messages)
+	}
+
+	got, _ := mgr.Get(ctx, "guild456", "thread123")
+	if got.Summary != "A and B happened" {
+		t.Errorf("Summary = %q, want 'A and B happened'", got.Summary)
+	}
+}
+
+func TestManager_CompactKeepsSystemPrompt(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	mgr := NewManager(client, time.Hour, 50)
+	ctx := context.Background()
+
+	mgr.Create(ctx, Conversation{ThreadID: "thread123", GuildID: "guild456", Model: "test/model"})
+	mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "system", Content: "Prompt"})
+	for _, content := range []string{"A", "B", "C"} {
+		mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "user", Content: content})
+	}
+
+	// The count covers history after the system prompt
+	if err := mgr.Compact(ctx, "guild456", "thread123", 2, "A and B happened"); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+
+	messages, _ := mgr.GetMessages(ctx, "guild456", "thread123")
+	if len(messages) != 2 || messages[0].Role != "system" || messages[0].Content != "Prompt" || messages[1].Content != "C" {
+		t.Errorf("Messages after compact = %+v, want Prompt, C", messages)
+	}
+}
+
+func TestManager_Fork(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	mgr := NewManager(client, time.Hour, 50)
+	ctx := context.Background()
+
+	child := Conversation{
+		ThreadID:   "thread789",
+		GuildID:    "guild456",
+		Model:      "test/model",
+		ParentID:   "thread123",
+		ForkedFrom: "101",
+	}
+	history := []Message{
+		{Role: "user", Content: "Question"},
+		{Role: "assistant", Content: "Answer"},
+	}
+
+	if err := mgr.Fork(ctx, child, history); err != nil {
+		t.Fatalf("Fork() error = %v", err)
+	}
+
+	got, err := mgr.Get(ctx, "guild456", "thread789")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.ParentID != "thread123" || got.ForkedFrom != "101" {
+		t.Errorf("Fork link = %q/%q, want thread123/101", got.ParentID, got.ForkedFrom)
+	}
+
+	messages, _ := mgr.GetMessages(ctx, "guild456", "thread789")
+	if len(messages) != 2 || messages[1].Content != "Answer" {
+		t.Errorf("Forked messages = %+v", messages)
+	}
+
+	forks, err := mgr.GetForks(ctx, "guild456", "thread123")
+	if err != nil {
+		t.Fatalf("GetForks() error = %v", err)
+	}
+	if len(forks) != 1 || forks[0] != "thread789" {
+		t.Errorf("GetForks() = %v, want [thread789]", forks)
+	}
+
+	// Deleting the fork drops it from the parent's forks
+	if err := mgr.Delete(ctx, "guild456", "thread789"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	forks, err = mgr.GetForks(ctx, "guild456", "thread123")
+	if err != nil {
+		t.Fatalf("GetForks() error = %v", err)
+	}
+	if len(forks) != 0 {
+		t.Errorf("GetForks() after Delete() = %v, want none", forks)
+	}
+}
+
+func TestManager_ReplaceMessages(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	mgr := NewManager(client, time.Hour, 50)
+	ctx := context.Background()
+
+	for _, content := range []string{"A", "B", "C"} {
+		mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "user", Content: content})
+	}
+
+	replacement := []Message{
+		{Role: "user", Content: "A (edited)"},
+	}
+	if err := mgr.ReplaceMessages(ctx, "guild456", "thread123", replacement); err != nil {
+		t.Fatalf("ReplaceMessages() error = %v", err)
+	}
+
+	messages, _ := mgr.GetMessages(ctx, "guild456", "thread123")
+	if len(messages) != 1 || messages[0].Content != "A (edited)" {
+		t.Errorf("Messages after replace = %+v, want [A (edited)]", messages)
+	}
+
+	// Replacing with nothing clears the history
+	if err := mgr.ReplaceMessages(ctx, "guild456", "thread123", nil); err != nil {
+		t.Fatalf("ReplaceMessages() error = %v", err)
+	}
+	messages, _ = mgr.GetMessages(ctx, "guild456", "thread123")
+	if len(messages) != 0 {
+		t.Errorf("Expected empty history, got %d messages", len(messages))
+	}
+}
+
+func TestManager_SetMessage(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	mgr := NewManager(client, time.Hour, 50)
+	ctx := context.Background()
+
+	for _, content := range []string{"A", "B", "C"} {
+		mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "user", Content: content})
+	}
+
+	if err := mgr.SetMessage(ctx, "guild456", "thread123", -1, Message{Role: "assistant", Content: "C (regenerated)"}); err != nil {
+		t.Fatalf("SetMessage() error = %v", err)
+	}
+	if err := mgr.SetMessage(ctx, "guild456", "thread123", 0, Message{Role: "user", Content: "A (edited)"}); err != nil {
+		t.Fatalf("SetMessage() error = %v", err)
+	}
+
+	messages, _ := mgr.GetMessages(ctx, "guild456", "thread123")
+	want := []string{"A (edited)", "B", "C (regenerated)"}
+	if len(messages) != len(want) {
+		t.Fatalf("Expected %d messages, got %d", len(want), len(messages))
+	}
+	for n, content := range want {
+		if messages[n].Content != content {
+			t.Errorf("Message %d = %q, want %q", n, messages[n].Content, content)
+		}
+	}
+
+	// Out of range indexes are an error rather than an append
+	if err := mgr.SetMessage(ctx, "guild456", "thread123", 5, Message{Role: "user", Content: "D"}); err == nil {
+		t.Error("SetMessage() out of range should fail")
+	}
+}
+
+// memoryArchive is an in-memory storage.Archive for tests
+type memoryArchive struct {
+	records map[string]storage.ArchiveRecord
+}
+
+func newMemoryArchive() *memoryArchive {
+	return &memoryArchive{records: make(map[string]storage.ArchiveRecord)}
+}
+
+func (a *memoryArchive) Save(ctx context.Context, record storage.ArchiveRecord) error {
+	a.records[record.GuildID+"/"+record.ThreadID] = record
+	return nil
+}
+
+func (a *memoryArchive) Load(ctx context.Context, guildID, threadID string) (*storage.ArchiveRecord, error) {
+	record, ok := a.records[guildID+"/"+threadID]
+	if !ok {
+		return nil, storage.ErrNotArchived
+	}
+	return &record, nil
+}
+
+func (a *memoryArchive) Delete(ctx context.Context, guildID, threadID string) error {
+	delete(a.records, guildID+"/"+threadID)
+	return nil
+}
+
+func (a *memoryArchive) Close() error {
+	return nil
+}
+
+func TestManager_ArchiveWriteThroughAndRehydrate(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	archive := newMemoryArchive()
+	mgr := NewManager(client, time.Hour, 50).WithArchive(archive, true)
+	ctx := context.Background()
+
+	conv := Conversation{
+		ThreadID: "thread123",
+		GuildID:  "guild456",
+		Model:    "test/model",
+		Title:    "Archived",
+	}
+	if err := mgr.Create(ctx, conv); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "user", Content: "Hello"})
+	mgr.AddMessage(ctx, "guild456", "thread123", Message{Role: "assistant", Content: "Hi"})
+
+	record, err := archive.Load(ctx, "guild456", "thread123")
+	if err != nil {
+		t.Fatalf("Conversation was not written through: %v", err)
+	}
+	if len(record.Messages) != 2 {
+		t.Errorf("Archived %d messages, want 2", len(record.Messages))
+	}
+
+	// Simulate the Redis TTL expiring
+	client.Redis().FlushDB(ctx)
+	if _, err := mgr.Get(ctx, "guild456", "thread123"); err == nil {
+		t.Fatal("Expected conversation to be gone from Redis")
+	}
+
+	got, err := mgr.Rehydrate(ctx, "guild456", "thread123")
+	if err != nil {
+		t.Fatalf("Rehydrate() error = %v", err)
+	}
+	if got.Title != "Archived" || got.Model != "test/model" {
+		t.Errorf("Rehydrated conversation = %+v", got)
+	}
+
+	messages, _ := mgr.GetMessages(ctx, "guild456", "thread123")
+	if len(messages) != 2 || messages[1].Content != "Hi" {
+		t.Errorf("Rehydrated messages = %+v", messages)
+	}
+
+	ttl := client.Redis().TTL(ctx, client.Keys().Conversation("guild456", "thread123")).Val()
+	if ttl <= 0 {
+		t.Errorf("Rehydrated conversation has no TTL: %v", ttl)
+	}
+
+	// Deleting removes the archived copy too
+	if err := mgr.Delete(ctx, "guild456", "thread123"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := mgr.Rehydrate(ctx, "guild456", "thread123"); !errors.Is(err, storage.ErrNotArchived) {
+		t.Errorf("Rehydrate() after delete error = %v, want ErrNotArchived", err)
+	}
+}
+
+func TestManager_ArchiveExpiring(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	archive := newMemoryArchive()
+	ctx := context.Background()
+
+	// Only the short-lived conversation falls inside the sweep window
+	NewManager(client, time.Minute, 50).Create(ctx, Conversation{ThreadID: "expiring", GuildID: "guild456"})
+	NewManager(client, 24*time.Hour, 50).Create(ctx, Conversation{ThreadID: "fresh", GuildID: "guild456"})
+
+	mgr := NewManager(client, time.Hour, 50).WithArchive(archive, false)
+	count, err := mgr.ArchiveExpiring(ctx, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("ArchiveExpiring() error = %v", err)
+	}
+	if count != 1 {
+		t.Errorf("ArchiveExpiring() = %d, want 1", count)
+	}
+	if _, err := archive.Load(ctx, "guild456", "expiring"); err != nil {
+		t.Errorf("Expiring conversation not archived: %v", err)
+	}
+	if _, err := archive.Load(ctx, "guild456", "fresh"); err == nil {
+		t.Error("Fresh conversation should not be archived")
+	}
+}
+
+func TestManager_List(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	mgr := NewManager(client, time.Hour, 50)
+	ctx := context.Background()
+
+	for _, conv := range []Conversation{
+		{ThreadID: "t1", GuildID: "guild456", UserID: "alice", Title: "First"},
+		{ThreadID: "t2", GuildID: "guild456", UserID: "bob", Title: "Second"},
+		{ThreadID: "t3", GuildID: "guild456", UserID: "alice", Title: "Third"},
+	} {
+		if err := mgr.Create(ctx, conv); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	// Activity moves a conversation to the top; scores have one-second resolution
+	client.Redis().ZAdd(ctx, client.Keys().UserConversations("guild456", "alice"), redis.Z{Score: 1, Member: "t3"})
+	mgr.AddMessage(ctx, "guild456", "t1", Message{Role: "user", Content: "Hello"})
+
+	mine, total, err := mgr.List(ctx, "guild456", "alice", 0, 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 2 || len(mine) != 2 {
+		t.Fatalf("List() = %d conversations (total %d), want 2", len(mine), total)
+	}
+	if mine[0].ThreadID != "t1" || mine[1].ThreadID != "t3" {
+		t.Errorf("List() order = %s, %s, want t1, t3", mine[0].ThreadID, mine[1].ThreadID)
+	}
+
+	// Paging
+	page, _, _ := mgr.List(ctx, "guild456", "alice", 1, 1)
+	if len(page) != 1 || page[0].ThreadID != "t3" {
+		t.Errorf("Second page = %+v, want [t3]", page)
+	}
+
+	// Guild-wide listing
+	_, total, _ = mgr.List(ctx, "guild456", "", 0, 10)
+	if total != 3 {
+		t.Errorf("Guild total = %d, want 3", total)
+	}
+
+	// Deleted and expired conversations leave the index
+	mgr.Delete(ctx, "guild456", "t1")
+	client.Redis().Del(ctx, client.Keys().Conversation("guild456", "t3"))
+
+	mine, total, _ = mgr.List(ctx, "guild456", "alice", 0, 10)
+	if len(mine) != 0 || total != 0 {
+		t.Errorf("List() after delete/expiry = %d conversations (total %d), want none", len(mine), total)
+	}
+}
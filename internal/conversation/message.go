@@ -3,17 +3,55 @@ package conversation
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Message represents a conversation message
+// Assistant messages also record how they were generated, for regenerations, exports and usage reports.
 type Message struct {
-	Role      string `json:"role"` // "system", "user", "assistant"
-	Content   string `json:"content"`
-	Tokens    int    `json:"tokens"`
-	MessageID string `json:"msg_id,omitempty"` // Discord message ID
+	Role             string    `json:"role"` // "system", "user", "assistant"
+	Content          string    `json:"content"`
+	Tokens           int       `json:"tokens"`
+	MessageID        string    `json:"msg_id,omitempty"`            // Discord message ID
+	AuthorID         string    `json:"author_id,omitempty"`         // Discord user who wrote a user message
+	AuthorName       string    `json:"author_name,omitempty"`       // Display name of the author when the message was sent
+	SentAt           int64     `json:"sent_at,omitempty"`           // Unix milliseconds when the message was posted
+	Model            string    `json:"model,omitempty"`             // Model reference that generated an assistant message
+	Provider         string    `json:"provider,omitempty"`          // Provider of that model
+	FinishReason     string    `json:"finish_reason,omitempty"`     // Why generation stopped, e.g. "stop" or "length"
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`     // Prompt tokens the provider billed for the reply
+	CompletionTokens int       `json:"completion_tokens,omitempty"` // Completion tokens the provider billed for the reply
+	LatencyMS        int64     `json:"latency_ms,omitempty"`        // How long the provider took to reply
+	Temperature      *float64  `json:"temperature,omitempty"`       // Sampling temperature of the request
+	Parts            []string  `json:"parts,omitempty"`             // Earlier Discord messages of a reply continued across several
+	Variants         []Message `json:"variants,omitempty"`          // Every generation of a regenerated reply, including the selected one
+	Variant          int       `json:"variant,omitempty"`           // Index of the selected variant; its content is the message's content
 }
 
+// FinishLength is the finish reason of a reply that was cut off by the token limit
+const FinishLength = "length"
+
+// discordEpoch is the Unix millisecond at which Discord snowflake timestamps start (2015-01-01)
+const discordEpoch = 1420070400000
+
+// Time returns when the message was posted, or the zero time if unknown
+func (m Message) Time() time.Time {
+	if m.SentAt == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(m.SentAt)
+}
+
+// Posting policies control who may post in a conversation thread
+const (
+	PostingAnyone    = "anyone"    // Anyone who can use models (default)
+	PostingOwner     = "owner"     // Only the conversation owner
+	PostingAllowlist = "allowlist" // The owner and the users in Posters
+)
+
 // Conversation represents conversation metadata
 type Conversation struct {
 	ThreadID     string
@@ -23,7 +61,14 @@ type Conversation struct {
 	Model        string
 	SystemPrompt string
 	Title        string
-	TokenCount   int
+	Retitled     bool     // Title has been re-checked after the first few turns
+	Summary      string   // Rolling summary of compacted (removed) history
+	Strategy     string   // Context window strategy override (empty = guild setting)
+	ParentID     string   // Thread this conversation was forked from (empty = not a fork)
+	ForkedFrom   string   // Discord message ID in the parent thread the fork was taken at
+	Posting      string   // Posting policy (empty = anyone)
+	Posters      []string // User IDs allowed to post under the allowlist policy
+	TokenCount   int      // Provider usage over the conversation's life; removing history doesn't reduce it
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
@@ -37,6 +82,13 @@ func (c *Conversation) ToMap() map[string]interface{} {
 		"model":         c.Model,
 		"system_prompt": c.SystemPrompt,
 		"title":         c.Title,
+		"retitled":      c.Retitled,
+		"summary":       c.Summary,
+		"strategy":      c.Strategy,
+		"parent_id":     c.ParentID,
+		"forked_from":   c.ForkedFrom,
+		"posting":       c.Posting,
+		"posters":       strings.Join(c.Posters, ","),
 		"token_count":   c.TokenCount,
 		"created_at":    c.CreatedAt.Unix(),
 		"updated_at":    c.UpdatedAt.Unix(),
@@ -52,6 +104,16 @@ func (c *Conversation) FromMap(threadID string, m map[string]string) error {
 	c.Model = m["model"]
 	c.SystemPrompt = m["system_prompt"]
 	c.Title = m["title"]
+	c.Retitled = m["retitled"] == "1"
+	c.Summary = m["summary"]
+	c.Strategy = m["strategy"]
+	c.ParentID = m["parent_id"]
+	c.ForkedFrom = m["forked_from"]
+	c.Posting = m["posting"]
+	c.Posters = nil
+	if m["posters"] != "" {
+		c.Posters = strings.Split(m["posters"], ",")
+	}
 
 	var tokenCount int64
 	if _, err := fmt.Sscanf(m["token_count"], "%d", &tokenCount); err == nil {
@@ -69,9 +131,182 @@ func (c *Conversation) FromMap(threadID string, m map[string]string) error {
 	return nil
 }
 
+// CanPost reports whether a user may post in the conversation under its posting policy
+func (c *Conversation) CanPost(userID string) bool {
+	if userID == c.UserID {
+		return true
+	}
+	switch c.Posting {
+	case PostingOwner:
+		return false
+	case PostingAllowlist:
+		return slices.Contains(c.Posters, userID)
+	default:
+		return true
+	}
+}
+
+// ValidPostingPolicy reports whether policy is a known posting policy
+func ValidPostingPolicy(policy string) bool {
+	switch policy {
+	case PostingAnyone, PostingOwner, PostingAllowlist:
+		return true
+	}
+	return false
+}
+
+// HasDiscordID reports whether the message was posted as the given Discord message, or continued from it
+func (m Message) HasDiscordID(id string) bool {
+	return id != "" && (m.MessageID == id || slices.Contains(m.Parts, id))
+}
+
+// DiscordIDs returns the Discord messages the message was posted as, in order
+func (m Message) DiscordIDs() []string {
+	if m.MessageID == "" {
+		return m.Parts
+	}
+	return append(m.Parts[:len(m.Parts):len(m.Parts)], m.MessageID)
+}
+
+// SetDiscordIDs records the Discord messages a reply was posted as, in order
+// The last one carries the reply's buttons and becomes its MessageID.
+func (m *Message) SetDiscordIDs(ids []string) {
+	m.MessageID, m.Parts = "", nil
+	if len(ids) == 0 {
+		return
+	}
+	m.MessageID = ids[len(ids)-1]
+	if len(ids) > 1 {
+		m.Parts = slices.Clone(ids[:len(ids)-1])
+	}
+}
+
+// Truncated reports whether the message is a reply that was cut off by the token limit
+func (m Message) Truncated() bool {
+	return m.Role == "assistant" && m.FinishReason == FinishLength
+}
+
+// AppendContinuation adds the continuation of a cut-off reply to it, combining their usage
+// If the continuation was posted as a new Discord message, it becomes the reply's message and
+// the earlier one is kept in Parts.
+func (m *Message) AppendContinuation(part Message) {
+	m.Content += part.Content
+	m.Tokens += part.Tokens
+	m.PromptTokens += part.PromptTokens
+	m.CompletionTokens += part.CompletionTokens
+	m.LatencyMS += part.LatencyMS
+	m.FinishReason = part.FinishReason
+
+	if part.MessageID != "" && part.MessageID != m.MessageID {
+		if m.MessageID != "" {
+			m.Parts = append(m.Parts, m.MessageID)
+		}
+		m.MessageID = part.MessageID
+	}
+}
+
+// VariantCount returns how many generations a reply has, 1 if it was never regenerated
+func (m Message) VariantCount() int {
+	return max(len(m.Variants), 1)
+}
+
+// AddVariant keeps a regenerated reply as a new variant of the message and selects it
+// The message keeps its Discord IDs; the variants only hold generated content and metadata.
+func (m *Message) AddVariant(v Message) {
+	if len(m.Variants) == 0 {
+		m.Variants = []Message{m.variant()}
+	} else {
+		m.Variants[m.Variant] = m.variant()
+	}
+	m.Variants = append(m.Variants, v.variant())
+	m.SelectVariant(len(m.Variants) - 1)
+}
+
+// SelectVariant makes variant n the message's content, the one used in future context
+// Returns false if there is no such variant.
+func (m *Message) SelectVariant(n int) bool {
+	if n < 0 || n >= len(m.Variants) {
+		return false
+	}
+
+	// Keep changes to the current variant, like continuations
+	m.Variants[m.Variant] = m.variant()
+
+	variants, messageID, parts := m.Variants, m.MessageID, m.Parts
+	*m = variants[n]
+	m.Variants, m.Variant, m.MessageID, m.Parts = variants, n, messageID, parts
+	return true
+}
+
+// variant returns the message's generated content and metadata, without Discord IDs or variants
+func (m Message) variant() Message {
+	m.MessageID, m.Parts, m.Variants, m.Variant = "", nil, nil, 0
+	return m
+}
+
+// FindMessage returns the index of the message with the given Discord ID, or -1
+func FindMessage(messages []Message, messageID string) int {
+	for i, msg := range messages {
+		if msg.HasDiscordID(messageID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// RemoveMessages drops the messages with the given Discord IDs from a history
+// Removing a user message also removes the assistant reply to its turn, even if other prompts were
+// batched after it. Returns the remaining messages and how many were removed.
+func RemoveMessages(messages []Message, messageIDs []string) ([]Message, int) {
+	ids := make(map[string]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		ids[id] = true
+	}
+
+	drop := make(map[int]bool)
+	for i, msg := range messages {
+		for id := range ids {
+			if msg.HasDiscordID(id) {
+				drop[i] = true
+				break
+			}
+		}
+
+		// Take the reply with the user turn
+		if drop[i] && msg.Role == "user" {
+			if reply := ReplyIndex(messages, i); reply >= 0 {
+				drop[reply] = true
+			}
+		}
+	}
+
+	kept := make([]Message, 0, len(messages))
+	for i, msg := range messages {
+		if !drop[i] {
+			kept = append(kept, msg)
+		}
+	}
+
+	return kept, len(messages) - len(kept)
+}
+
+// TotalTokens sums the token counts of messages
+func TotalTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += msg.Tokens
+	}
+	return total
+}
+
 // MarshalMessage converts a Message to JSON for storage
 func MarshalMessage(m Message) (string, error) {
-	data, err := json.Marshal(m)
+	stored := storedMessage{Message: m}
+	if m.Role == "assistant" {
+		// Always written for replies so zero usage isn't mistaken for a legacy entry
+		stored.CompletionTokens = &m.CompletionTokens
+	}
+	data, err := json.Marshal(stored)
 	if err != nil {
 		return "", err
 	}
@@ -79,8 +314,65 @@ func MarshalMessage(m Message) (string, error) {
 }
 
 // UnmarshalMessage converts JSON to a Message
+// Entries stored before per-message metadata was recorded are filled in where it can be derived: the
+// posting time from the Discord message ID and an assistant reply's completion tokens from its token count.
 func UnmarshalMessage(data string) (Message, error) {
-	var m Message
-	err := json.Unmarshal([]byte(data), &m)
-	return m, err
+	var stored storedMessage
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return stored.Message, err
+	}
+
+	m := stored.Message
+	if m.SentAt == 0 {
+		m.SentAt = snowflakeMillis(m.MessageID)
+	}
+	if stored.CompletionTokens != nil {
+		m.CompletionTokens = *stored.CompletionTokens
+	} else if m.Role == "assistant" {
+		m.CompletionTokens = m.Tokens
+	}
+
+	return m, nil
+}
+
+// storedMessage is the stored form of a Message
+// Its completion tokens are a pointer so entries that predate them can be told apart from replies that used none.
+type storedMessage struct {
+	Message
+	CompletionTokens *int `json:"completion_tokens,omitempty"`
+}
+
+// snowflakeMillis returns the Unix milliseconds encoded in a Discord ID, or 0 if it isn't one
+func snowflakeMillis(id string) int64 {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n == 0 {
+		return 0
+	}
+	return int64(n>>22) + discordEpoch
+}
+
+// LastExchange returns the index where a history's last exchange starts: its final reply and
+// the prompts that reply answered. Returns len(messages) if the history ends with neither.
+func LastExchange(messages []Message) int {
+	start := len(messages)
+	if start > 0 && messages[start-1].Role == "assistant" {
+		start--
+	}
+	for start > 0 && messages[start-1].Role == "user" {
+		start--
+	}
+	return start
+}
+
+// ReplyIndex returns the index of the reply that answered the prompt at idx, skipping any prompts
+// batched after it into the same turn. Returns -1 if the prompt hasn't been answered.
+func ReplyIndex(messages []Message, idx int) int {
+	next := idx + 1
+	for next < len(messages) && messages[next].Role == "user" {
+		next++
+	}
+	if next < len(messages) && messages[next].Role == "assistant" {
+		return next
+	}
+	return -1
 }
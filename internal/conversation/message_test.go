@@ -0,0 +1,322 @@
+package conversation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindMessage(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "System prompt"},
+		{Role: "user", Content: "Question", MessageID: "100"},
+		{Role: "assistant", Content: "Answer", MessageID: "101"},
+	}
+
+	if got := FindMessage(messages, "101"); got != 2 {
+		t.Errorf("FindMessage(101) = %d, want 2", got)
+	}
+	if got := FindMessage(messages, "999"); got != -1 {
+		t.Errorf("FindMessage(999) = %d, want -1", got)
+	}
+	if got := FindMessage(messages, ""); got != -1 {
+		t.Errorf("FindMessage(\"\") = %d, want -1", got)
+	}
+}
+
+func TestRemoveMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "System prompt", Tokens: 5},
+		{Role: "user", Content: "Q1", MessageID: "100", Tokens: 10},
+		{Role: "assistant", Content: "A1", MessageID: "101", Tokens: 20},
+		{Role: "user", Content: "Q2", MessageID: "102", Tokens: 10},
+		{Role: "assistant", Content: "A2", MessageID: "103", Tokens: 20},
+	}
+
+	tests := []struct {
+		name    string
+		ids     []string
+		want    []string
+		removed int
+	}{
+		{
+			name:    "user turn takes its reply",
+			ids:     []string{"100"},
+			want:    []string{"System prompt", "Q2", "A2"},
+			removed: 2,
+		},
+		{
+			name:    "assistant reply alone",
+			ids:     []string{"103"},
+			want:    []string{"System prompt", "Q1", "A1", "Q2"},
+			removed: 1,
+		},
+		{
+			name:    "bulk delete",
+			ids:     []string{"101", "102"},
+			want:    []string{"System prompt", "Q1"},
+			removed: 3,
+		},
+		{
+			name:    "unknown message",
+			ids:     []string{"999"},
+			want:    []string{"System prompt", "Q1", "A1", "Q2", "A2"},
+			removed: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, removed := RemoveMessages(messages, tt.ids)
+			if removed != tt.removed {
+				t.Errorf("removed = %d, want %d", removed, tt.removed)
+			}
+			if len(kept) != len(tt.want) {
+				t.Fatalf("kept %d messages, want %d", len(kept), len(tt.want))
+			}
+			for i, content := range tt.want {
+				if kept[i].Content != content {
+					t.Errorf("kept[%d] = %q, want %q", i, kept[i].Content, content)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveMessages_BatchedTurn(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "System prompt"},
+		{Role: "user", Content: "Q1", MessageID: "100"},
+		{Role: "user", Content: "Q2", MessageID: "101"},
+		{Role: "assistant", Content: "A1", MessageID: "102"},
+		{Role: "user", Content: "Q3", MessageID: "103"},
+	}
+
+	kept, removed := RemoveMessages(messages, []string{"100"})
+	if removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+	want := []string{"System prompt", "Q2", "Q3"}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %d messages, want %d", len(kept), len(want))
+	}
+	for i, content := range want {
+		if kept[i].Content != content {
+			t.Errorf("kept[%d] = %q, want %q", i, kept[i].Content, content)
+		}
+	}
+}
+
+func TestTotalTokens(t *testing.T) {
+	messages := []Message{
+		{Role: "user", Content: "Q1", Tokens: 10},
+		{Role: "assistant", Content: "A1", Tokens: 20},
+	}
+
+	if got := TotalTokens(messages); got != 30 {
+		t.Errorf("TotalTokens() = %d, want 30", got)
+	}
+}
+
+func TestUnmarshalMessage_Legacy(t *testing.T) {
+	// Entries stored before per-message metadata only had these fields
+	msg, err := UnmarshalMessage(`{"role":"assistant","content":"Answer","tokens":42,"msg_id":"1187458129347301376"}`)
+	if err != nil {
+		t.Fatalf("UnmarshalMessage() error = %v", err)
+	}
+
+	if msg.CompletionTokens != 42 {
+		t.Errorf("CompletionTokens = %d, want 42", msg.CompletionTokens)
+	}
+	want := time.Date(2023, 12, 21, 18, 14, 39, 0, time.UTC)
+	if got := msg.Time().UTC().Truncate(time.Second); !got.Equal(want) {
+		t.Errorf("Time() = %v, want %v", got, want)
+	}
+	if msg.Model != "" || msg.Temperature != nil {
+		t.Errorf("Unexpected metadata on legacy entry: %+v", msg)
+	}
+
+	// Messages without a Discord ID have no known time
+	msg, err = UnmarshalMessage(`{"role":"system","content":"Prompt","tokens":5}`)
+	if err != nil {
+		t.Fatalf("UnmarshalMessage() error = %v", err)
+	}
+	if !msg.Time().IsZero() || msg.CompletionTokens != 0 {
+		t.Errorf("Unexpected derived metadata on system message: %+v", msg)
+	}
+}
+
+func TestUnmarshalMessage_ZeroCompletionTokens(t *testing.T) {
+	// Replies the provider reported no completion tokens for aren't legacy entries
+	data, err := MarshalMessage(Message{Role: "assistant", Content: "", Tokens: 4, Model: "openai/gpt-4o"})
+	if err != nil {
+		t.Fatalf("MarshalMessage() error = %v", err)
+	}
+	msg, err := UnmarshalMessage(data)
+	if err != nil {
+		t.Fatalf("UnmarshalMessage() error = %v", err)
+	}
+	if msg.CompletionTokens != 0 {
+		t.Errorf("CompletionTokens = %d, want 0", msg.CompletionTokens)
+	}
+
+	msg, err = UnmarshalMessage(`{"role":"assistant","content":"Answer","tokens":42,"completion_tokens":0}`)
+	if err != nil {
+		t.Fatalf("UnmarshalMessage() error = %v", err)
+	}
+	if msg.CompletionTokens != 0 {
+		t.Errorf("CompletionTokens = %d, want 0", msg.CompletionTokens)
+	}
+}
+
+func TestMarshalMessage_RoundTrip(t *testing.T) {
+	temperature := 0.0
+	original := Message{
+		Role:             "assistant",
+		Content:          "Answer",
+		Tokens:           30,
+		MessageID:        "1187458129347301376",
+		SentAt:           1703182479941,
+		Model:            "openai/gpt-4o",
+		Provider:         "openai",
+		FinishReason:     "length",
+		PromptTokens:     120,
+		CompletionTokens: 30,
+		LatencyMS:        850,
+		Temperature:      &temperature,
+	}
+
+	data, err := MarshalMessage(original)
+	if err != nil {
+		t.Fatalf("MarshalMessage() error = %v", err)
+	}
+	decoded, err := UnmarshalMessage(data)
+	if err != nil {
+		t.Fatalf("UnmarshalMessage() error = %v", err)
+	}
+
+	if decoded.Temperature == nil || *decoded.Temperature != 0 {
+		t.Errorf("Temperature = %v, want 0", decoded.Temperature)
+	}
+	decoded.Temperature, original.Temperature = nil, nil
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("Round trip = %+v, want %+v", decoded, original)
+	}
+}
+
+func TestMessage_AppendContinuation(t *testing.T) {
+	reply := Message{Role: "assistant", Content: "The quick brown", Tokens: 10, CompletionTokens: 10, FinishReason: FinishLength, MessageID: "100"}
+	if !reply.Truncated() {
+		t.Fatal("Expected the reply to be truncated")
+	}
+
+	// Edited in place: same Discord message
+	reply.AppendContinuation(Message{Content: " fox jumps", Tokens: 5, CompletionTokens: 5, FinishReason: FinishLength, MessageID: "100"})
+	if reply.Content != "The quick brown fox jumps" || reply.Tokens != 15 || reply.CompletionTokens != 15 {
+		t.Errorf("After in-place continuation = %+v", reply)
+	}
+	if len(reply.Parts) != 0 {
+		t.Errorf("Parts = %v, want none", reply.Parts)
+	}
+
+	// Posted as a new Discord message
+	reply.AppendContinuation(Message{Content: " over the dog.", Tokens: 4, CompletionTokens: 4, FinishReason: "stop", MessageID: "105"})
+	if reply.Truncated() || reply.MessageID != "105" || !reflect.DeepEqual(reply.Parts, []string{"100"}) {
+		t.Errorf("After new-message continuation = %+v", reply)
+	}
+
+	// Either part finds the reply
+	messages := []Message{{Role: "user", Content: "Q", MessageID: "99"}, reply}
+	if FindMessage(messages, "100") != 1 || FindMessage(messages, "105") != 1 {
+		t.Error("FindMessage() should match every part of a continued reply")
+	}
+	if kept, removed := RemoveMessages(messages, []string{"100"}); removed != 1 || len(kept) != 1 {
+		t.Errorf("RemoveMessages() removed %d, kept %d", removed, len(kept))
+	}
+
+	// Re-posted across three messages
+	reply.SetDiscordIDs([]string{"100", "110", "111"})
+	if reply.MessageID != "111" || !reflect.DeepEqual(reply.DiscordIDs(), []string{"100", "110", "111"}) {
+		t.Errorf("After SetDiscordIDs() = %s %v", reply.MessageID, reply.Parts)
+	}
+}
+
+func TestMessage_Variants(t *testing.T) {
+	reply := Message{Role: "assistant", Content: "First", Tokens: 3, Model: "a/one", MessageID: "100", Parts: []string{"99"}}
+	if reply.VariantCount() != 1 {
+		t.Errorf("VariantCount() = %d, want 1", reply.VariantCount())
+	}
+
+	// Regenerating keeps the Discord IDs and selects the new variant
+	reply.AddVariant(Message{Role: "assistant", Content: "Second", Tokens: 4, Model: "a/two", MessageID: "200"})
+	if reply.VariantCount() != 2 || reply.Variant != 1 || reply.Content != "Second" || reply.Model != "a/two" {
+		t.Errorf("After AddVariant() = %+v", reply)
+	}
+	if reply.MessageID != "100" || !reflect.DeepEqual(reply.Parts, []string{"99"}) {
+		t.Errorf("Discord IDs = %s %v, want 100 [99]", reply.MessageID, reply.Parts)
+	}
+
+	// Changes to the selected variant survive switching away and back
+	reply.AppendContinuation(Message{Content: " more", Tokens: 2, MessageID: "100"})
+	if !reply.SelectVariant(0) || reply.Content != "First" || reply.Tokens != 3 {
+		t.Errorf("After SelectVariant(0) = %+v", reply)
+	}
+	if !reply.SelectVariant(1) || reply.Content != "Second more" || reply.Tokens != 6 {
+		t.Errorf("After SelectVariant(1) = %+v", reply)
+	}
+	if reply.SelectVariant(2) || reply.SelectVariant(-1) {
+		t.Error("SelectVariant() should reject missing variants")
+	}
+
+	// Variants don't nest or carry Discord IDs
+	for i, v := range reply.Variants {
+		if v.MessageID != "" || v.Parts != nil || v.Variants != nil {
+			t.Errorf("Variant %d = %+v, want content only", i, v)
+		}
+	}
+}
+
+func TestLastExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []Message
+		want     int
+	}{
+		{"empty", nil, 0},
+		{"system only", []Message{{Role: "system"}}, 1},
+		{"prompt and reply", []Message{{Role: "system"}, {Role: "user"}, {Role: "assistant"}, {Role: "user"}, {Role: "assistant"}}, 3},
+		{"batched prompts", []Message{{Role: "system"}, {Role: "user"}, {Role: "assistant"}, {Role: "user"}, {Role: "user"}, {Role: "assistant"}}, 3},
+		{"unanswered prompt", []Message{{Role: "system"}, {Role: "user"}, {Role: "assistant"}, {Role: "user"}}, 3},
+		{"reply without prompt", []Message{{Role: "system"}, {Role: "assistant"}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LastExchange(tt.messages); got != tt.want {
+				t.Errorf("LastExchange() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplyIndex(t *testing.T) {
+	messages := []Message{{Role: "system"}, {Role: "user"}, {Role: "assistant"}, {Role: "user"}, {Role: "user"}, {Role: "assistant"}, {Role: "user"}}
+	tests := []struct {
+		name string
+		idx  int
+		want int
+	}{
+		{"single prompt", 1, 2},
+		{"first of batch", 3, 5},
+		{"last of batch", 4, 5},
+		{"unanswered prompt", 6, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReplyIndex(messages, tt.idx); got != tt.want {
+				t.Errorf("ReplyIndex(%d) = %d, want %d", tt.idx, got, tt.want)
+			}
+		})
+	}
+}
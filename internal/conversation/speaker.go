@@ -0,0 +1,49 @@
+package conversation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxSpeakerNameLength is the longest name accepted by OpenAI-compatible "name" fields
+const maxSpeakerNameLength = 64
+
+// MultipleSpeakers reports whether more than one author wrote the user messages
+// Speaker attribution is only needed once a thread is shared.
+func MultipleSpeakers(messages []Message) bool {
+	first := ""
+	for _, msg := range messages {
+		if msg.Role != "user" || msg.AuthorID == "" {
+			continue
+		}
+		if first == "" {
+			first = msg.AuthorID
+		} else if msg.AuthorID != first {
+			return true
+		}
+	}
+	return false
+}
+
+// SpeakerName converts a display name to a valid message "name" field
+// Providers only accept letters, digits, underscores and hyphens, up to 64 characters.
+func SpeakerName(displayName string) string {
+	var sb strings.Builder
+	for _, r := range displayName {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), r == '_', r == '-':
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			sb.WriteRune('_')
+		}
+		if sb.Len() == maxSpeakerNameLength {
+			break
+		}
+	}
+	return strings.Trim(sb.String(), "_")
+}
+
+// SpeakerPrefix renders a speaker prefix format such as "{name}: " for a display name
+func SpeakerPrefix(format, displayName string) string {
+	return strings.ReplaceAll(format, "{name}", displayName)
+}
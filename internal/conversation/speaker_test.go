@@ -0,0 +1,79 @@
+package conversation
+
+import "testing"
+
+func TestMultipleSpeakers(t *testing.T) {
+	solo := []Message{
+		{Role: "system", Content: "Prompt"},
+		{Role: "user", Content: "Hi", AuthorID: "alice"},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", Content: "Again", AuthorID: "alice"},
+		{Role: "user", Content: "Imported"},
+	}
+	if MultipleSpeakers(solo) {
+		t.Error("Expected a single speaker")
+	}
+
+	shared := append(solo, Message{Role: "user", Content: "Me too", AuthorID: "bob"})
+	if !MultipleSpeakers(shared) {
+		t.Error("Expected multiple speakers")
+	}
+}
+
+func TestSpeakerName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"alice", "alice"},
+		{"Alice Smith", "Alice_Smith"},
+		{"  ~bob.the-builder~ ", "bobthe-builder"},
+		{"Zoë 🚀", "Zo"},
+		{"日本", ""},
+	}
+
+	for _, tt := range tests {
+		if got := SpeakerName(tt.in); got != tt.want {
+			t.Errorf("SpeakerName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := ""
+	for i := 0; i < 100; i++ {
+		long += "a"
+	}
+	if got := SpeakerName(long); len(got) != 64 {
+		t.Errorf("SpeakerName() length = %d, want 64", len(got))
+	}
+}
+
+func TestSpeakerPrefix(t *testing.T) {
+	if got := SpeakerPrefix("[{name}] ", "Alice"); got != "[Alice] " {
+		t.Errorf("SpeakerPrefix() = %q, want '[Alice] '", got)
+	}
+}
+
+func TestConversation_CanPost(t *testing.T) {
+	conv := Conversation{UserID: "owner", Posters: []string{"friend"}}
+
+	tests := []struct {
+		policy string
+		user   string
+		want   bool
+	}{
+		{"", "stranger", true},
+		{PostingAnyone, "stranger", true},
+		{PostingOwner, "owner", true},
+		{PostingOwner, "friend", false},
+		{PostingAllowlist, "friend", true},
+		{PostingAllowlist, "stranger", false},
+		{PostingAllowlist, "owner", true},
+	}
+
+	for _, tt := range tests {
+		conv.Posting = tt.policy
+		if got := conv.CanPost(tt.user); got != tt.want {
+			t.Errorf("CanPost(%q) with policy %q = %v, want %v", tt.user, tt.policy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Context window strategy names
+const (
+	StrategyTruncate      = "truncate"       // Keep the newest messages that fit
+	StrategyPinFirst      = "pin_first"      // Always keep the first user message, then the newest
+	StrategySlidingWindow = "sliding_window" // Keep only the last N turns
+	StrategySummary       = "summary"        // Like truncate, but overflow is summarized by the caller
+	StrategyError         = "error"          // Refuse to build a context once history no longer fits
+)
+
+// ErrContextFull is returned by the error strategy when history doesn't fit
+var ErrContextFull = errors.New("conversation history exceeds the context window")
+
+// Strategy decides which history messages go into a context window
+type Strategy interface {
+	// Name returns the strategy's config name
+	Name() string
+	// Select returns the indexes of history messages to keep, in chronological order.
+	// Every message has Tokens set; budget is what's left after the system prompt and summary.
+	Select(history []Message, budget int) ([]int, error)
+}
+
+// StrategyNames returns all available strategy names
+func StrategyNames() []string {
+	return []string{
+		StrategyTruncate,
+		StrategyPinFirst,
+		StrategySlidingWindow,
+		StrategySummary,
+		StrategyError,
+	}
+}
+
+// NewStrategy creates a strategy by name
+// windowTurns is only used by the sliding window strategy
+func NewStrategy(name string, windowTurns int) (Strategy, error) {
+	switch name {
+	case StrategyTruncate, "":
+		return TruncateStrategy{}, nil
+	case StrategyPinFirst:
+		return PinFirstStrategy{}, nil
+	case StrategySlidingWindow:
+		return SlidingWindowStrategy{Turns: windowTurns}, nil
+	case StrategySummary:
+		return SummaryStrategy{}, nil
+	case StrategyError:
+		return ErrorStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown context strategy: %s", name)
+	}
+}
+
+// TruncateStrategy keeps the newest messages that fit and drops the rest
+type TruncateStrategy struct{}
+
+// Name returns the strategy's config name
+func (TruncateStrategy) Name() string { return StrategyTruncate }
+
+// Select keeps messages from newest to oldest until the budget runs out
+func (TruncateStrategy) Select(history []Message, budget int) ([]int, error) {
+	return newestThatFit(history, 0, budget), nil
+}
+
+// PinFirstStrategy always keeps the first user message (the original request)
+// and fills the rest of the budget with the newest messages
+type PinFirstStrategy struct{}
+
+// Name returns the strategy's config name
+func (PinFirstStrategy) Name() string { return StrategyPinFirst }
+
+// Select pins the first user message if it fits, then keeps the newest messages
+func (PinFirstStrategy) Select(history []Message, budget int) ([]int, error) {
+	first := -1
+	for i, msg := range history {
+		if msg.Role == "user" {
+			first = i
+			break
+		}
+	}
+
+	if first < 0 || history[first].Tokens > budget {
+		return newestThatFit(history, 0, budget), nil
+	}
+
+	rest := newestThatFit(history[first+1:], 0, budget-history[first].Tokens)
+	kept := []int{first}
+	for _, idx := range rest {
+		kept = append(kept, first+1+idx)
+	}
+	return kept, nil
+}
+
+// SlidingWindowStrategy keeps only the last Turns turns (a turn starts at a user message)
+type SlidingWindowStrategy struct {
+	Turns int
+}
+
+// Name returns the strategy's config name
+func (SlidingWindowStrategy) Name() string { return StrategySlidingWindow }
+
+// Select keeps the newest messages of the last Turns turns that fit in the budget
+func (sw SlidingWindowStrategy) Select(history []Message, budget int) ([]int, error) {
+	start := 0
+	if sw.Turns > 0 {
+		turns := 0
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Role != "user" {
+				continue
+			}
+			turns++
+			if turns == sw.Turns {
+				start = i
+				break
+			}
+		}
+	}
+
+	return newestThatFit(history, start, budget), nil
+}
+
+// SummaryStrategy selects like TruncateStrategy; the caller summarizes what was dropped
+type SummaryStrategy struct{}
+
+// Name returns the strategy's config name
+func (SummaryStrategy) Name() string { return StrategySummary }
+
+// Select keeps messages from newest to oldest until the budget runs out
+func (SummaryStrategy) Select(history []Message, budget int) ([]int, error) {
+	return newestThatFit(history, 0, budget), nil
+}
+
+// ErrorStrategy keeps the whole history or fails with ErrContextFull
+type ErrorStrategy struct{}
+
+// Name returns the strategy's config name
+func (ErrorStrategy) Name() string { return StrategyError }
+
+// Select keeps every message, or returns ErrContextFull if they don't all fit
+func (ErrorStrategy) Select(history []Message, budget int) ([]int, error) {
+	kept := make([]int, len(history))
+	total := 0
+	for i, msg := range history {
+		total += msg.Tokens
+		kept[i] = i
+	}
+
+	if total > budget {
+		return nil, ErrContextFull
+	}
+	return kept, nil
+}
+
+// newestThatFit walks history[start:] from newest to oldest and returns the
+// indexes (chronological) of the messages that fit before the budget runs out
+func newestThatFit(history []Message, start, budget int) []int {
+	var kept []int
+	total := 0
+
+	for i := len(history) - 1; i >= start; i-- {
+		if total+history[i].Tokens > budget {
+			// Can't fit more messages
+			break
+		}
+		kept = append(kept, i)
+		total += history[i].Tokens
+	}
+
+	// Reverse into chronological order
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+
+	return kept
+}
@@ -0,0 +1,177 @@
+package conversation
+
+import (
+	"errors"
+	"testing"
+)
+
+func strategyHistory() []Message {
+	return []Message{
+		{Role: "user", Content: "Original requirements", Tokens: 10},
+		{Role: "assistant", Content: "Ack 1", Tokens: 10},
+		{Role: "user", Content: "Follow-up 2", Tokens: 10},
+		{Role: "assistant", Content: "Ack 2", Tokens: 10},
+		{Role: "user", Content: "Follow-up 3", Tokens: 10},
+		{Role: "assistant", Content: "Ack 3", Tokens: 10},
+		{Role: "user", Content: "Latest", Tokens: 10},
+	}
+}
+
+func contents(history []Message, kept []int) []string {
+	out := make([]string, len(kept))
+	for i, idx := range kept {
+		out[i] = history[idx].Content
+	}
+	return out
+}
+
+func assertContents(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("kept %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("kept %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTruncateStrategy_Select(t *testing.T) {
+	history := strategyHistory()
+
+	kept, err := TruncateStrategy{}.Select(history, 30)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	assertContents(t, contents(history, kept), []string{"Follow-up 3", "Ack 3", "Latest"})
+}
+
+func TestPinFirstStrategy_Select(t *testing.T) {
+	history := strategyHistory()
+
+	kept, err := PinFirstStrategy{}.Select(history, 30)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	assertContents(t, contents(history, kept), []string{"Original requirements", "Ack 3", "Latest"})
+}
+
+func TestPinFirstStrategy_FirstMessageTooLarge(t *testing.T) {
+	history := []Message{
+		{Role: "user", Content: "Huge", Tokens: 100},
+		{Role: "assistant", Content: "Ack", Tokens: 10},
+		{Role: "user", Content: "Latest", Tokens: 10},
+	}
+
+	kept, err := PinFirstStrategy{}.Select(history, 30)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	assertContents(t, contents(history, kept), []string{"Ack", "Latest"})
+}
+
+func TestSlidingWindowStrategy_Select(t *testing.T) {
+	history := strategyHistory()
+
+	// Plenty of budget, but only the last 2 turns are kept
+	kept, err := SlidingWindowStrategy{Turns: 2}.Select(history, 1000)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	assertContents(t, contents(history, kept), []string{"Follow-up 3", "Ack 3", "Latest"})
+
+	// Budget still applies inside the window
+	kept, err = SlidingWindowStrategy{Turns: 2}.Select(history, 20)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	assertContents(t, contents(history, kept), []string{"Ack 3", "Latest"})
+}
+
+func TestSummaryStrategy_Select(t *testing.T) {
+	history := strategyHistory()
+
+	kept, err := SummaryStrategy{}.Select(history, 20)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	assertContents(t, contents(history, kept), []string{"Ack 3", "Latest"})
+}
+
+func TestErrorStrategy_Select(t *testing.T) {
+	history := strategyHistory()
+
+	kept, err := ErrorStrategy{}.Select(history, 1000)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(kept) != len(history) {
+		t.Errorf("kept %d messages, want all %d", len(kept), len(history))
+	}
+
+	_, err = ErrorStrategy{}.Select(history, 30)
+	if !errors.Is(err, ErrContextFull) {
+		t.Errorf("Select() error = %v, want ErrContextFull", err)
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	for _, name := range StrategyNames() {
+		strategy, err := NewStrategy(name, 5)
+		if err != nil {
+			t.Fatalf("NewStrategy(%q) error = %v", name, err)
+		}
+		if strategy.Name() != name {
+			t.Errorf("NewStrategy(%q).Name() = %q", name, strategy.Name())
+		}
+	}
+
+	if _, err := NewStrategy("bogus", 0); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+}
+
+func TestContextBuilder_WithStrategy(t *testing.T) {
+	cb := NewContextBuilder(90, 20).WithStrategy(PinFirstStrategy{})
+
+	messages := []Message{
+		{Role: "system", Content: "Stored system prompt", Tokens: 10},
+		{Role: "user", Content: "Original requirements", Tokens: 20},
+		{Role: "assistant", Content: "Old response", Tokens: 30},
+		{Role: "user", Content: "Latest", Tokens: 20},
+	}
+
+	result, err := cb.BuildWithSummary(messages, "", "System.", "gpt-4")
+	if err != nil {
+		t.Fatalf("BuildWithSummary() error = %v", err)
+	}
+
+	got := make([]string, 0, len(result.Messages))
+	for _, msg := range result.Messages[1:] {
+		got = append(got, msg.Content)
+	}
+	assertContents(t, got, []string{"Original requirements", "Latest"})
+
+	if len(result.Dropped) != 1 || result.Dropped[0].Content != "Old response" {
+		t.Errorf("Dropped = %+v, want Old response", result.Dropped)
+	}
+}
+
+func TestContextBuilder_ErrorStrategyFull(t *testing.T) {
+	cb := NewContextBuilder(50, 10).WithStrategy(ErrorStrategy{})
+
+	messages := []Message{
+		{Role: "user", Content: "Old message", Tokens: 30},
+		{Role: "user", Content: "New message", Tokens: 30},
+	}
+
+	_, err := cb.BuildWithSummary(messages, "", "System.", "gpt-4")
+	if !errors.Is(err, ErrContextFull) {
+		t.Errorf("BuildWithSummary() error = %v, want ErrContextFull", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/s33g/discord-prompter/internal/config"
@@ -93,7 +94,8 @@ func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, erro
 }
 
 // GenerateTitle generates a short title for a conversation
-func (c *Client) GenerateTitle(ctx context.Context, model, userPrompt string) (string, error) {
+// Usage is zero when the request failed and the prompt fallback was used
+func (c *Client) GenerateTitle(ctx context.Context, model, userPrompt string) (string, Usage, error) {
 	req := ChatRequest{
 		Model: model,
 		Messages: []Message{
@@ -117,21 +119,101 @@ func (c *Client) GenerateTitle(ctx context.Context, model, userPrompt string) (s
 		if len(title) > 80 {
 			title = title[:77] + "..."
 		}
-		return title, nil // Don't fail the whole request if title generation fails
+		return title, Usage{}, nil // Don't fail the whole request if title generation fails
 	}
 
 	if len(resp.Choices) == 0 {
-		return userPrompt[:min(80, len(userPrompt))], nil
+		return userPrompt[:min(80, len(userPrompt))], resp.Usage, nil
 	}
 
-	title := resp.Choices[0].Message.Content
+	return cleanTitle(resp.Choices[0].Message.Content), resp.Usage, nil
+}
+
+// SuggestTitle asks the model whether a conversation's title still fits its recent messages
+// Returns the current title unchanged when the model thinks it still fits
+func (c *Client) SuggestTitle(ctx context.Context, model, currentTitle, transcript string) (string, Usage, error) {
+	req := ChatRequest{
+		Model: model,
+		Messages: []Message{
+			{
+				Role: "system",
+				Content: "You maintain titles for chat conversations. Given the current title and the conversation so far, " +
+					"reply with a short, descriptive title (max 100 characters) that reflects what the conversation is now about. " +
+					"If the current title still fits, reply with it unchanged. Reply with ONLY the title, no quotes or formatting.",
+			},
+			{
+				Role:    "user",
+				Content: fmt.Sprintf("Current title: %s\n\nConversation:\n%s", currentTitle, transcript),
+			},
+		},
+		MaxTokens:   50,
+		Temperature: 0.3,
+	}
+
+	resp, err := c.Chat(ctx, req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	if len(resp.Choices) == 0 {
+		return currentTitle, resp.Usage, nil
+	}
+
+	title := cleanTitle(resp.Choices[0].Message.Content)
+	if title == "" {
+		title = currentTitle
+	}
+
+	return title, resp.Usage, nil
+}
+
+// Summarize condenses a transcript into a summary, folding in any previous summary
+func (c *Client) Summarize(ctx context.Context, model, previousSummary, transcript string) (string, Usage, error) {
+	content := transcript
+	if previousSummary != "" {
+		content = fmt.Sprintf("Previous summary:\n%s\n\nNew messages:\n%s", previousSummary, transcript)
+	}
+
+	req := ChatRequest{
+		Model: model,
+		Messages: []Message{
+			{
+				Role: "system",
+				Content: "Summarize the following conversation so it can replace the original messages as context for the rest of the conversation. " +
+					"Merge in the previous summary if one is given. Preserve requirements, decisions, constraints, names, code identifiers and open questions. " +
+					"Be concise and factual. Reply with ONLY the summary.",
+			},
+			{
+				Role:    "user",
+				Content: content,
+			},
+		},
+		MaxTokens:   600,
+		Temperature: 0.2,
+	}
+
+	resp, err := c.Chat(ctx, req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+		return "", resp.Usage, fmt.Errorf("empty summary from model")
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Message.Content), resp.Usage, nil
+}
+
+// cleanTitle strips quotes and whitespace from a generated title and caps its length
+func cleanTitle(title string) string {
+	title = strings.Trim(strings.TrimSpace(title), "\"'`")
 
 	// Trim title if too long
 	if len(title) > 100 {
 		title = title[:97] + "..."
 	}
 
-	return title, nil
+	return title
 }
 
 func min(a, b int) int {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/s33g/discord-prompter/internal/config"
@@ -157,7 +158,7 @@ func TestClient_GenerateTitle(t *testing.T) {
 
 	// Generate title
 	ctx := context.Background()
-	title, err := client.GenerateTitle(ctx, "test-model", "Explain Docker networking")
+	title, _, err := client.GenerateTitle(ctx, "test-model", "Explain Docker networking")
 	if err != nil {
 		t.Fatalf("GenerateTitle() error = %v", err)
 	}
@@ -184,7 +185,7 @@ func TestClient_GenerateTitleFallback(t *testing.T) {
 	// Generate title (should fallback gracefully)
 	ctx := context.Background()
 	prompt := "This is a very long prompt that should be truncated to 80 characters maximum when used as a fallback title"
-	title, err := client.GenerateTitle(ctx, "test-model", prompt)
+	title, usage, err := client.GenerateTitle(ctx, "test-model", prompt)
 
 	// Should not error (falls back)
 	if err != nil {
@@ -195,6 +196,84 @@ func TestClient_GenerateTitleFallback(t *testing.T) {
 	if len(title) > 100 {
 		t.Errorf("Title length = %d, should be <= 100", len(title))
 	}
+
+	// Fallback costs nothing
+	if usage.TotalTokens != 0 {
+		t.Errorf("Usage.TotalTokens = %d, want 0 for fallback", usage.TotalTokens)
+	}
+}
+
+func TestClient_SuggestTitle(t *testing.T) {
+	// Create mock server that echoes back a quoted title
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if !strings.Contains(req.Messages[1].Content, "Current title: Docker Networking") {
+			t.Errorf("Request should include the current title, got %q", req.Messages[1].Content)
+		}
+
+		resp := ChatResponse{
+			Choices: []Choice{{Message: Message{Content: " \"Kubernetes Ingress Setup\"\n"}}},
+			Usage:   Usage{PromptTokens: 40, CompletionTokens: 5, TotalTokens: 45},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	provider := &config.Provider{
+		Name:    "test",
+		BaseURL: server.URL,
+	}
+	client, _ := NewClient(provider)
+
+	title, usage, err := client.SuggestTitle(context.Background(), "test-model", "Docker Networking", "user: how do I set up ingress?")
+	if err != nil {
+		t.Fatalf("SuggestTitle() error = %v", err)
+	}
+
+	if title != "Kubernetes Ingress Setup" {
+		t.Errorf("Title = %q, want 'Kubernetes Ingress Setup'", title)
+	}
+	if usage.TotalTokens != 45 {
+		t.Errorf("Usage.TotalTokens = %d, want 45", usage.TotalTokens)
+	}
+}
+
+func TestClient_Summarize(t *testing.T) {
+	// Create mock server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		if !strings.Contains(req.Messages[1].Content, "Previous summary:\nUser wants a CLI in Go") {
+			t.Errorf("Request should include the previous summary, got %q", req.Messages[1].Content)
+		}
+
+		resp := ChatResponse{
+			Choices: []Choice{{Message: Message{Content: "User wants a Go CLI using cobra.\n"}}},
+			Usage:   Usage{TotalTokens: 120},
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	provider := &config.Provider{
+		Name:    "test",
+		BaseURL: server.URL,
+	}
+	client, _ := NewClient(provider)
+
+	summary, usage, err := client.Summarize(context.Background(), "test-model", "User wants a CLI in Go", "user: use cobra")
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if summary != "User wants a Go CLI using cobra." {
+		t.Errorf("Summary = %q, want trimmed model output", summary)
+	}
+	if usage.TotalTokens != 120 {
+		t.Errorf("Usage.TotalTokens = %d, want 120", usage.TotalTokens)
+	}
 }
 
 func TestRegistry_GetClient(t *testing.T) {
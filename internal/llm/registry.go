@@ -74,22 +74,56 @@ func (r *Registry) Chat(ctx context.Context, modelRef string, messages []Message
 }
 
 // GenerateTitle generates a title for a conversation
-func (r *Registry) GenerateTitle(ctx context.Context, modelRef, userPrompt string) (string, error) {
+func (r *Registry) GenerateTitle(ctx context.Context, modelRef, userPrompt string) (string, Usage, error) {
 	// Resolve model reference
 	provider, model, err := r.config.ResolveModel(modelRef)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	// Get client
 	client, err := r.GetClient(provider.Name)
 	if err != nil {
-		return "", err
+		return "", Usage{}, err
 	}
 
 	return client.GenerateTitle(ctx, model.ID, userPrompt)
 }
 
+// SuggestTitle asks a model for an updated conversation title
+func (r *Registry) SuggestTitle(ctx context.Context, modelRef, currentTitle, transcript string) (string, Usage, error) {
+	// Resolve model reference
+	provider, model, err := r.config.ResolveModel(modelRef)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	// Get client
+	client, err := r.GetClient(provider.Name)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	return client.SuggestTitle(ctx, model.ID, currentTitle, transcript)
+}
+
+// Summarize condenses conversation history with a model
+func (r *Registry) Summarize(ctx context.Context, modelRef, previousSummary, transcript string) (string, Usage, error) {
+	// Resolve model reference
+	provider, model, err := r.config.ResolveModel(modelRef)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	// Get client
+	client, err := r.GetClient(provider.Name)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	return client.Summarize(ctx, model.ID, previousSummary, transcript)
+}
+
 // Reload reinitializes clients after config reload
 func (r *Registry) Reload(cfg *config.Config) error {
 	r.mu.Lock()
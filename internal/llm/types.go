@@ -15,6 +15,7 @@ type ChatRequest struct {
 type Message struct {
 	Role    string `json:"role"` // system, user, assistant
 	Content string `json:"content"`
+	Name    string `json:"name,omitempty"` // Speaker of a user message in shared conversations
 }
 
 // ChatResponse represents a chat completion response
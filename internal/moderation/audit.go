@@ -0,0 +1,81 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+// maxExcerptLen caps how much of the moderated text is kept in audit records
+const maxExcerptLen = 300
+
+// Record is a single moderation audit entry
+type Record struct {
+	Time      time.Time `json:"time"`
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	UserID    string    `json:"user_id"`
+	Stage     Stage     `json:"stage"`
+	Action    Action    `json:"action"`
+	Source    string    `json:"source"`
+	Rule      string    `json:"rule"`
+	Excerpt   string    `json:"excerpt"`
+}
+
+// newRecord builds an audit record for a finding
+func newRecord(subj Subject, stage Stage, f Finding, text string) Record {
+	excerpt := text
+	if runes := []rune(text); len(runes) > maxExcerptLen {
+		excerpt = string(runes[:maxExcerptLen-3]) + "..."
+	}
+
+	return Record{
+		Time:      time.Now(),
+		GuildID:   subj.GuildID,
+		ChannelID: subj.ChannelID,
+		UserID:    subj.UserID,
+		Stage:     stage,
+		Action:    f.Action,
+		Source:    f.Source,
+		Rule:      f.Rule,
+		Excerpt:   excerpt,
+	}
+}
+
+// Auditor stores moderation audit records
+type Auditor interface {
+	Record(ctx context.Context, rec Record, limit int) error
+}
+
+// RedisAuditor keeps a capped per-guild audit list in Redis
+type RedisAuditor struct {
+	client *storage.Client
+}
+
+// NewRedisAuditor creates a Redis-backed auditor
+func NewRedisAuditor(client *storage.Client) *RedisAuditor {
+	return &RedisAuditor{client: client}
+}
+
+// Record prepends a record to the guild's audit list, keeping at most limit entries
+func (a *RedisAuditor) Record(ctx context.Context, rec Record, limit int) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	key := a.client.Keys().ModerationAudit(rec.GuildID)
+
+	pipe := a.client.Redis().Pipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, int64(limit-1))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+
+	return nil
+}
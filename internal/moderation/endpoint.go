@@ -0,0 +1,144 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s33g/discord-prompter/internal/config"
+)
+
+// moderationRequest is the body of an OpenAI-compatible /moderations request
+type moderationRequest struct {
+	Input string `json:"input"`
+	Model string `json:"model,omitempty"`
+}
+
+// moderationResponse is the body of an OpenAI-compatible /moderations response
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// EndpointChecker calls an OpenAI-compatible moderation endpoint
+type EndpointChecker struct {
+	httpClient *http.Client
+	endpoint   config.ModerationEndpoint
+	apiKey     string
+}
+
+// NewEndpointChecker creates a checker for a moderation endpoint
+func NewEndpointChecker(endpoint config.ModerationEndpoint) *EndpointChecker {
+	apiKey := ""
+	if endpoint.APIKeyEnv != "" {
+		apiKey = os.Getenv(endpoint.APIKeyEnv)
+	}
+
+	return &EndpointChecker{
+		httpClient: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		endpoint: endpoint,
+		apiKey:   apiKey,
+	}
+}
+
+// Check sends the text to the endpoint and reports flagged categories
+func (ec *EndpointChecker) Check(ctx context.Context, stage Stage, text string) ([]Finding, error) {
+	if !appliesTo(ec.endpoint.Apply, stage) || strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(moderationRequest{Input: text, Model: ec.endpoint.Model})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+
+	url := strings.TrimSuffix(ec.endpoint.BaseURL, "/") + "/moderations"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if ec.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+ec.apiKey)
+	}
+
+	resp, err := ec.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation API error (%d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var modResp moderationResponse
+	if err := json.Unmarshal(respBody, &modResp); err != nil {
+		return nil, fmt.Errorf("failed to parse moderation response: %w", err)
+	}
+
+	var findings []Finding
+	for _, result := range modResp.Results {
+		if !result.Flagged {
+			continue
+		}
+
+		for _, category := range ec.matchedCategories(result.Categories) {
+			findings = append(findings, Finding{
+				Source: "endpoint",
+				Rule:   category,
+				Action: Action(ec.endpoint.Action),
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+// matchedCategories returns flagged categories that the endpoint config cares about
+func (ec *EndpointChecker) matchedCategories(categories map[string]bool) []string {
+	var matched []string
+	for category, flagged := range categories {
+		if !flagged {
+			continue
+		}
+		if len(ec.endpoint.Categories) > 0 && !containsString(ec.endpoint.Categories, category) {
+			continue
+		}
+		matched = append(matched, category)
+	}
+
+	// Flagged without a specific category still counts when no filter is set
+	if len(matched) == 0 && len(ec.endpoint.Categories) == 0 {
+		matched = append(matched, "flagged")
+	}
+
+	sort.Strings(matched)
+	return matched
+}
+
+func containsString(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action is what happens when a moderation check matches
+type Action string
+
+const (
+	// ActionFlag records the match and notifies the moderator channel
+	ActionFlag Action = "flag"
+	// ActionWarn lets the content through with a visible warning
+	ActionWarn Action = "warn"
+	// ActionRedact replaces the matched text before it is used
+	ActionRedact Action = "redact"
+	// ActionBlock stops the content entirely
+	ActionBlock Action = "block"
+)
+
+// severity orders actions so the strongest one wins
+func (a Action) severity() int {
+	switch a {
+	case ActionFlag:
+		return 1
+	case ActionWarn:
+		return 2
+	case ActionRedact:
+		return 3
+	case ActionBlock:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Stage identifies which side of the exchange is being checked
+type Stage string
+
+const (
+	// StageInput is the user's prompt before it reaches the model
+	StageInput Stage = "input"
+	// StageOutput is the model's response before it reaches the channel
+	StageOutput Stage = "output"
+)
+
+// appliesTo reports whether a rule's "apply" setting covers the stage
+func appliesTo(apply string, stage Stage) bool {
+	return apply == "" || apply == "both" || apply == string(stage)
+}
+
+// Finding is a single match reported by a checker
+type Finding struct {
+	Source  string   // "rule" or "endpoint"
+	Rule    string   // Rule name or endpoint category
+	Action  Action   // Configured action for the match
+	Matches []string // Matched text (used for redaction)
+}
+
+// Checker inspects text and reports findings
+type Checker interface {
+	Check(ctx context.Context, stage Stage, text string) ([]Finding, error)
+}
+
+// Result is the combined outcome of all checkers for one piece of text
+type Result struct {
+	Stage    Stage
+	Content  string // Content after redaction
+	Action   Action // Strongest action among findings ("" when clean)
+	Findings []Finding
+}
+
+// Blocked reports whether the content must not be used
+func (r *Result) Blocked() bool {
+	return r != nil && r.Action == ActionBlock
+}
+
+// Has reports whether any finding requested the given action
+func (r *Result) Has(action Action) bool {
+	if r == nil {
+		return false
+	}
+	for _, f := range r.Findings {
+		if f.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the names of the rules that matched
+func (r *Result) Rules() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.Findings))
+	for _, f := range r.Findings {
+		names = append(names, f.Rule)
+	}
+	return names
+}
+
+// BlockedError is returned when moderation stops an exchange
+type BlockedError struct {
+	Stage Stage
+	Rules []string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s blocked by moderation (%v)", e.Stage, e.Rules)
+}
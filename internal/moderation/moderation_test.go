@@ -0,0 +1,244 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/llm"
+)
+
+type fakeAuditor struct {
+	records []Record
+}
+
+func (f *fakeAuditor) Record(ctx context.Context, rec Record, limit int) error {
+	f.records = append(f.records, rec)
+	return nil
+}
+
+func getTestConfig(rules []config.ModerationRule, endpoint *config.ModerationEndpoint) *config.Config {
+	return &config.Config{
+		Guilds: []config.GuildConfig{
+			{
+				ID: "test-guild",
+				Moderation: config.ModerationConfig{
+					Enabled:      true,
+					Rules:        rules,
+					Endpoint:     endpoint,
+					ModChannelID: "mod-channel",
+				},
+			},
+		},
+	}
+}
+
+func TestRuleChecker_Check(t *testing.T) {
+	rc, err := NewRuleChecker([]config.ModerationRule{
+		{Name: "secrets", Pattern: `sk-[A-Za-z0-9]{8,}`, Action: "redact"},
+		{Name: "slurs", Keywords: []string{"badword"}, Action: "block", Apply: "input"},
+	})
+	if err != nil {
+		t.Fatalf("NewRuleChecker() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		stage     Stage
+		text      string
+		wantRules []string
+	}{
+		{name: "clean", stage: StageInput, text: "hello there", wantRules: nil},
+		{name: "regex match", stage: StageOutput, text: "key is sk-abcdef123456", wantRules: []string{"secrets"}},
+		{name: "keyword case insensitive", stage: StageInput, text: "BadWord!", wantRules: []string{"slurs"}},
+		{name: "keyword whole word only", stage: StageInput, text: "badwords", wantRules: nil},
+		{name: "apply limited to input", stage: StageOutput, text: "badword", wantRules: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := rc.Check(context.Background(), tt.stage, tt.text)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(findings) != len(tt.wantRules) {
+				t.Fatalf("Check() returned %d findings, want %d", len(findings), len(tt.wantRules))
+			}
+			for i, want := range tt.wantRules {
+				if findings[i].Rule != want {
+					t.Errorf("finding %d rule = %s, want %s", i, findings[i].Rule, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEndpointChecker_Check(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moderations" {
+			t.Errorf("Expected /moderations, got %s", r.URL.Path)
+		}
+
+		var req moderationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		flagged := req.Input == "something hateful"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{
+				{
+					"flagged":    flagged,
+					"categories": map[string]bool{"hate": flagged, "violence": false},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	ec := NewEndpointChecker(config.ModerationEndpoint{BaseURL: server.URL, Action: "warn"})
+	ctx := context.Background()
+
+	findings, err := ec.Check(ctx, StageInput, "something hateful")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(findings) != 1 || findings[0].Rule != "hate" || findings[0].Action != ActionWarn {
+		t.Errorf("Check() findings = %+v, want one warn finding for hate", findings)
+	}
+
+	findings, err = ec.Check(ctx, StageInput, "something nice")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Check() findings = %+v, want none", findings)
+	}
+}
+
+func TestPipeline_ChatRedactsAndAudits(t *testing.T) {
+	cfg := getTestConfig([]config.ModerationRule{
+		{Name: "emails", Pattern: `[a-z]+@example\.com`, Action: "redact"},
+		{Name: "watch", Keywords: []string{"exploit"}, Action: "flag"},
+	}, nil)
+
+	auditor := &fakeAuditor{}
+	var flagged []Record
+	p, err := NewPipeline(cfg, auditor, func(channelID string, rec Record) {
+		flagged = append(flagged, rec)
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	var sent []llm.Message
+	chat := func(ctx context.Context, modelRef string, messages []llm.Message, maxTokens int, temperature float64) (*llm.ChatResponse, error) {
+		sent = messages
+		return &llm.ChatResponse{
+			Choices: []llm.Choice{{Message: llm.Message{Role: "assistant", Content: "Here is an exploit"}}},
+		}, nil
+	}
+
+	req := Request{
+		Subject:  Subject{GuildID: "test-guild", ChannelID: "thread", UserID: "user"},
+		ModelRef: "test/model",
+		Messages: []llm.Message{
+			{Role: "system", Content: "Be helpful."},
+			{Role: "user", Content: "Mail bob@example.com"},
+		},
+	}
+
+	exchange, err := p.Chat(context.Background(), req, chat)
+	if err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+
+	if sent[1].Content != "Mail "+RedactionText {
+		t.Errorf("Model received %q, want redacted content", sent[1].Content)
+	}
+	if req.Messages[1].Content != "Mail bob@example.com" {
+		t.Error("Chat() should not modify the caller's messages")
+	}
+	if exchange.Input.Content != "Mail "+RedactionText {
+		t.Errorf("Input content = %q, want redacted content", exchange.Input.Content)
+	}
+	if !exchange.Output.Has(ActionFlag) {
+		t.Error("Output should be flagged")
+	}
+	if len(auditor.records) != 2 {
+		t.Errorf("Got %d audit records, want 2", len(auditor.records))
+	}
+	if len(flagged) != 1 || flagged[0].Rule != "watch" {
+		t.Errorf("Flag notifications = %+v, want one for watch", flagged)
+	}
+}
+
+func TestPipeline_ChatBlocksInput(t *testing.T) {
+	cfg := getTestConfig([]config.ModerationRule{
+		{Name: "banned", Keywords: []string{"forbidden"}, Action: "block"},
+	}, nil)
+
+	p, err := NewPipeline(cfg, &fakeAuditor{}, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	called := false
+	chat := func(ctx context.Context, modelRef string, messages []llm.Message, maxTokens int, temperature float64) (*llm.ChatResponse, error) {
+		called = true
+		return &llm.ChatResponse{}, nil
+	}
+
+	_, err = p.Chat(context.Background(), Request{
+		Subject:  Subject{GuildID: "test-guild"},
+		Messages: []llm.Message{{Role: "user", Content: "Tell me the forbidden thing"}},
+	}, chat)
+
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Chat() error = %v, want BlockedError", err)
+	}
+	if blocked.Stage != StageInput {
+		t.Errorf("Blocked stage = %s, want input", blocked.Stage)
+	}
+	if called {
+		t.Error("Model should not be called for blocked input")
+	}
+}
+
+func TestPipeline_DisabledGuildPassesThrough(t *testing.T) {
+	cfg := getTestConfig([]config.ModerationRule{
+		{Name: "banned", Keywords: []string{"forbidden"}, Action: "block"},
+	}, nil)
+	cfg.Guilds[0].Moderation.Enabled = false
+
+	p, err := NewPipeline(cfg, &fakeAuditor{}, nil, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewPipeline() error = %v", err)
+	}
+
+	result, err := p.Check(context.Background(), Subject{GuildID: "test-guild"}, StageInput, "forbidden")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.Action != "" || result.Content != "forbidden" {
+		t.Errorf("Check() = %+v, want untouched result", result)
+	}
+}
+
+func TestNewRecord_ExcerptKeepsRunesWhole(t *testing.T) {
+	text := strings.Repeat("é", maxExcerptLen+10)
+
+	rec := newRecord(Subject{GuildID: "test-guild"}, StageInput, Finding{Source: "rule", Rule: "banned"}, text)
+	if !utf8.ValidString(rec.Excerpt) {
+		t.Fatalf("Excerpt is not valid UTF-8: %q", rec.Excerpt)
+	}
+	if n := utf8.RuneCountInString(rec.Excerpt); n != maxExcerptLen {
+		t.Errorf("Excerpt has %d characters, want %d", n, maxExcerptLen)
+	}
+}
@@ -0,0 +1,213 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/llm"
+)
+
+// Subject identifies who and where a moderated exchange belongs to
+type Subject struct {
+	GuildID   string
+	ChannelID string
+	UserID    string
+}
+
+// FlagFunc delivers a flagged record to the guild's moderator channel
+type FlagFunc func(modChannelID string, rec Record)
+
+// ChatFunc sends a chat completion request (matches llm.Registry.Chat)
+type ChatFunc func(ctx context.Context, modelRef string, messages []llm.Message, maxTokens int, temperature float64) (*llm.ChatResponse, error)
+
+// Request describes a chat completion to run through moderation
+type Request struct {
+	Subject     Subject
+	ModelRef    string
+	Messages    []llm.Message
+	MaxTokens   int
+	Temperature float64
+	SkipInput   bool // Input was already moderated (e.g. regenerations)
+}
+
+// Exchange is the moderated result of a chat completion
+type Exchange struct {
+	Response *llm.ChatResponse
+	Input    *Result
+	Output   *Result
+	Latency  time.Duration // How long the model took to reply, excluding moderation
+}
+
+// Pipeline runs per-guild moderation checks around chat completions
+type Pipeline struct {
+	mu       sync.RWMutex
+	config   *config.Config
+	checkers map[string][]Checker // key: guild ID
+	auditor  Auditor
+	onFlag   FlagFunc
+	logger   zerolog.Logger
+}
+
+// NewPipeline creates a moderation pipeline for all configured guilds
+func NewPipeline(cfg *config.Config, auditor Auditor, onFlag FlagFunc, logger zerolog.Logger) (*Pipeline, error) {
+	p := &Pipeline{
+		auditor: auditor,
+		onFlag:  onFlag,
+		logger:  logger,
+	}
+
+	if err := p.Reload(cfg); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Reload rebuilds guild checkers after a config reload
+func (p *Pipeline) Reload(cfg *config.Config) error {
+	checkers := make(map[string][]Checker)
+
+	for _, guild := range cfg.Guilds {
+		if !guild.Moderation.Enabled {
+			continue
+		}
+
+		var guildCheckers []Checker
+		if len(guild.Moderation.Rules) > 0 {
+			rc, err := NewRuleChecker(guild.Moderation.Rules)
+			if err != nil {
+				return fmt.Errorf("guild %s: %w", guild.ID, err)
+			}
+			guildCheckers = append(guildCheckers, rc)
+		}
+		if guild.Moderation.Endpoint != nil {
+			guildCheckers = append(guildCheckers, NewEndpointChecker(*guild.Moderation.Endpoint))
+		}
+
+		checkers[guild.ID] = guildCheckers
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = cfg
+	p.checkers = checkers
+
+	return nil
+}
+
+// Check runs the guild's checkers against text and records any findings
+func (p *Pipeline) Check(ctx context.Context, subj Subject, stage Stage, text string) (*Result, error) {
+	p.mu.RLock()
+	checkers := p.checkers[subj.GuildID]
+	cfg := p.config
+	p.mu.RUnlock()
+
+	result := &Result{Stage: stage, Content: text}
+	if len(checkers) == 0 {
+		return result, nil
+	}
+
+	for _, checker := range checkers {
+		findings, err := checker.Check(ctx, stage, text)
+		if err != nil {
+			// Fail open - a broken moderation endpoint shouldn't take the bot down
+			p.logger.Warn().Err(err).Str("guild", subj.GuildID).Str("stage", string(stage)).Msg("Moderation check failed")
+			continue
+		}
+		result.Findings = append(result.Findings, findings...)
+	}
+
+	for _, f := range result.Findings {
+		if f.Action.severity() > result.Action.severity() {
+			result.Action = f.Action
+		}
+	}
+	result.Content = redact(text, result.Findings)
+
+	if len(result.Findings) > 0 {
+		guildCfg, err := cfg.GetGuild(subj.GuildID)
+		if err != nil {
+			return result, err
+		}
+		p.record(ctx, guildCfg, subj, result, text)
+	}
+
+	return result, nil
+}
+
+// record writes audit records and sends flag notifications for a result
+func (p *Pipeline) record(ctx context.Context, guildCfg *config.GuildConfig, subj Subject, result *Result, text string) {
+	for _, f := range result.Findings {
+		rec := newRecord(subj, result.Stage, f, text)
+
+		if p.auditor != nil {
+			if err := p.auditor.Record(ctx, rec, guildCfg.GetModerationAuditLimit()); err != nil {
+				p.logger.Error().Err(err).Str("guild", subj.GuildID).Msg("Failed to write moderation audit record")
+			}
+		}
+
+		if f.Action == ActionFlag && p.onFlag != nil && guildCfg.Moderation.ModChannelID != "" {
+			p.onFlag(guildCfg.Moderation.ModChannelID, rec)
+		}
+	}
+}
+
+// Chat moderates the newest user message, sends the request and moderates the reply
+func (p *Pipeline) Chat(ctx context.Context, req Request, chat ChatFunc) (*Exchange, error) {
+	exchange := &Exchange{}
+	messages := req.Messages
+
+	// Moderate the newest user message (older turns were checked when they were sent)
+	if !req.SkipInput {
+		for idx := len(messages) - 1; idx >= 0; idx-- {
+			if messages[idx].Role != "user" {
+				continue
+			}
+
+			result, err := p.Check(ctx, req.Subject, StageInput, messages[idx].Content)
+			if err != nil {
+				return exchange, err
+			}
+			exchange.Input = result
+
+			if result.Blocked() {
+				return exchange, &BlockedError{Stage: StageInput, Rules: result.Rules()}
+			}
+			if result.Content != messages[idx].Content {
+				messages = append([]llm.Message(nil), messages...)
+				messages[idx].Content = result.Content
+			}
+			break
+		}
+	}
+
+	start := time.Now()
+	response, err := chat(ctx, req.ModelRef, messages, req.MaxTokens, req.Temperature)
+	if err != nil {
+		return exchange, err
+	}
+	exchange.Response = response
+	exchange.Latency = time.Since(start)
+
+	if len(response.Choices) == 0 {
+		return exchange, nil
+	}
+
+	// Moderate the model's reply
+	result, err := p.Check(ctx, req.Subject, StageOutput, response.Choices[0].Message.Content)
+	if err != nil {
+		return exchange, err
+	}
+	exchange.Output = result
+
+	if result.Blocked() {
+		return exchange, &BlockedError{Stage: StageOutput, Rules: result.Rules()}
+	}
+	response.Choices[0].Message.Content = result.Content
+
+	return exchange, nil
+}
@@ -0,0 +1,100 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/s33g/discord-prompter/internal/config"
+)
+
+// RedactionText replaces redacted matches
+const RedactionText = "[redacted]"
+
+// compiledRule is a moderation rule with its expression compiled
+type compiledRule struct {
+	name   string
+	re     *regexp.Regexp
+	action Action
+	apply  string
+}
+
+// RuleChecker matches text against local regex and keyword rules
+type RuleChecker struct {
+	rules []compiledRule
+}
+
+// NewRuleChecker compiles guild moderation rules
+func NewRuleChecker(rules []config.ModerationRule) (*RuleChecker, error) {
+	rc := &RuleChecker{rules: make([]compiledRule, 0, len(rules))}
+
+	for _, rule := range rules {
+		var parts []string
+		if rule.Pattern != "" {
+			parts = append(parts, "(?:"+rule.Pattern+")")
+		}
+		if len(rule.Keywords) > 0 {
+			words := make([]string, len(rule.Keywords))
+			for i, kw := range rule.Keywords {
+				words[i] = regexp.QuoteMeta(kw)
+			}
+			parts = append(parts, `(?i:\b(?:`+strings.Join(words, "|")+`)\b)`)
+		}
+
+		re, err := regexp.Compile(strings.Join(parts, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile rule %s: %w", rule.Name, err)
+		}
+
+		rc.rules = append(rc.rules, compiledRule{
+			name:   rule.Name,
+			re:     re,
+			action: Action(rule.Action),
+			apply:  rule.Apply,
+		})
+	}
+
+	return rc, nil
+}
+
+// Check reports every rule that matches the text
+func (rc *RuleChecker) Check(ctx context.Context, stage Stage, text string) ([]Finding, error) {
+	var findings []Finding
+
+	for _, rule := range rc.rules {
+		if !appliesTo(rule.apply, stage) {
+			continue
+		}
+
+		matches := rule.re.FindAllString(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		findings = append(findings, Finding{
+			Source:  "rule",
+			Rule:    rule.name,
+			Action:  rule.action,
+			Matches: matches,
+		})
+	}
+
+	return findings, nil
+}
+
+// redact replaces all matches of redact findings in the text
+func redact(text string, findings []Finding) string {
+	for _, f := range findings {
+		if f.Action != ActionRedact {
+			continue
+		}
+		for _, m := range f.Matches {
+			if m == "" {
+				continue
+			}
+			text = strings.ReplaceAll(text, m, RedactionText)
+		}
+	}
+	return text
+}
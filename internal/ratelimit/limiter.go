@@ -217,6 +217,34 @@ func (l *Limiter) CheckTokenLimit(ctx context.Context, guildID, userID string, l
 	}, nil
 }
 
+// AddTokens records token usage without enforcing the limit
+// Used for usage that has already happened, such as title generation
+func (l *Limiter) AddTokens(ctx context.Context, guildID, userID string, limit config.TokenLimit, tokens int) error {
+	if limit.Bypass || limit.PeriodHours <= 0 || tokens <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	periodSeconds := int64(limit.PeriodHours * 3600)
+	periodStart := (now.Unix() / periodSeconds) * periodSeconds
+
+	key := l.client.Keys().TokenLimit(guildID, userID, periodStart)
+
+	used, err := l.client.Redis().IncrBy(ctx, key, int64(tokens)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to add token usage: %w", err)
+	}
+
+	// First write in this period - set expiry like the token limit script does
+	if used == int64(tokens) {
+		if err := l.client.Redis().Expire(ctx, key, time.Duration(periodSeconds)*time.Second).Err(); err != nil {
+			return fmt.Errorf("failed to set token usage TTL: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // GetCurrentUsage returns current token usage without incrementing
 func (l *Limiter) GetCurrentUsage(ctx context.Context, guildID, userID string, periodHours int) (int, error) {
 	now := time.Now()
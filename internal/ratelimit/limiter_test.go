@@ -216,6 +216,47 @@ func TestLimiter_GetCurrentUsage(t *testing.T) {
 	}
 }
 
+func TestLimiter_AddTokens(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	limiter, err := NewLimiter(client)
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+
+	ctx := context.Background()
+	limit := config.TokenLimit{
+		TokensPerPeriod: 100,
+		PeriodHours:     1,
+	}
+
+	// AddTokens records usage even past the limit
+	if err := limiter.AddTokens(ctx, "guild1", "user1", limit, 80); err != nil {
+		t.Fatalf("AddTokens() error = %v", err)
+	}
+	if err := limiter.AddTokens(ctx, "guild1", "user1", limit, 40); err != nil {
+		t.Fatalf("AddTokens() error = %v", err)
+	}
+
+	usage, err := limiter.GetCurrentUsage(ctx, "guild1", "user1", 1)
+	if err != nil {
+		t.Fatalf("GetCurrentUsage() error = %v", err)
+	}
+	if usage != 120 {
+		t.Errorf("Usage = %d, want 120", usage)
+	}
+
+	// Further checks are now rejected
+	result, err := limiter.CheckTokenLimit(ctx, "guild1", "user1", limit, 1)
+	if err != nil {
+		t.Fatalf("CheckTokenLimit() error = %v", err)
+	}
+	if result.Allowed {
+		t.Error("CheckTokenLimit() should reject after AddTokens exceeded the limit")
+	}
+}
+
 func TestLimiter_DifferentUsers(t *testing.T) {
 	client := getTestClient(t)
 	defer client.Close()
@@ -0,0 +1,117 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CodeBlock is a fenced code block found in a reply
+type CodeBlock struct {
+	Language string // Language named on the opening fence, lowercased, or ""
+	Code     string
+}
+
+// extensions maps fence languages to file extensions where they differ from the language name
+var extensions = map[string]string{
+	"bash":       "sh",
+	"shell":      "sh",
+	"zsh":        "sh",
+	"console":    "sh",
+	"python":     "py",
+	"python3":    "py",
+	"javascript": "js",
+	"typescript": "ts",
+	"golang":     "go",
+	"rust":       "rs",
+	"ruby":       "rb",
+	"kotlin":     "kt",
+	"csharp":     "cs",
+	"c#":         "cs",
+	"c++":        "cpp",
+	"perl":       "pl",
+	"powershell": "ps1",
+	"markdown":   "md",
+	"yml":        "yaml",
+	"patch":      "diff",
+	"text":       "txt",
+	"plaintext":  "txt",
+}
+
+// fileNames maps fence languages whose files are conventionally named rather than given an extension
+var fileNames = map[string]string{
+	"dockerfile": "Dockerfile",
+	"makefile":   "Makefile",
+}
+
+// CodeBlocks returns the fenced code blocks in content, in order
+// A block left open at the end, as in a reply cut off by the token limit, is included.
+func CodeBlocks(content string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var lines []string
+	indent := ""
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "```") {
+			if current != nil {
+				lines = append(lines, strings.TrimPrefix(line, indent))
+			}
+			continue
+		}
+
+		if current != nil {
+			current.Code = strings.Join(lines, "\n")
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+
+		// Blocks inside lists are indented along with their fences
+		current = &CodeBlock{}
+		if fields := strings.Fields(strings.TrimPrefix(trimmed, "```")); len(fields) > 0 {
+			current.Language = strings.ToLower(fields[0])
+		}
+		indent = line[:strings.Index(line, "```")]
+		lines = nil
+	}
+
+	if current != nil && len(lines) > 0 {
+		current.Code = strings.Join(lines, "\n")
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// Filename returns a file name for the nth (1-based) of count code blocks
+// The extension comes from the fence language; unknown or missing languages get .txt.
+func Filename(block CodeBlock, n, count int) string {
+	base := "snippet"
+	if count > 1 {
+		base = fmt.Sprintf("snippet-%d", n)
+	}
+
+	if name, ok := fileNames[block.Language]; ok {
+		if count > 1 {
+			return fmt.Sprintf("%s-%d", name, n)
+		}
+		return name
+	}
+	return base + "." + extension(block.Language)
+}
+
+// extension returns the file extension for a fence language
+func extension(language string) string {
+	if ext, ok := extensions[language]; ok {
+		return ext
+	}
+	if language == "" || len(language) > 8 {
+		return "txt"
+	}
+	for _, r := range language {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return "txt"
+		}
+	}
+	return language
+}
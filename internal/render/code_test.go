@@ -0,0 +1,47 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	content := "Here you go:\n\n```Python\ndef main():\n    print('hi')\n```\n\nThen:\n\n1. Build it\n   ```\n   make\n     all\n   ```\n\n```go\npackage main"
+
+	want := []CodeBlock{
+		{Language: "python", Code: "def main():\n    print('hi')"},
+		{Language: "", Code: "make\n  all"},
+		{Language: "go", Code: "package main"},
+	}
+	if got := CodeBlocks(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("CodeBlocks() = %q, want %q", got, want)
+	}
+
+	if got := CodeBlocks("No code here"); got != nil {
+		t.Errorf("CodeBlocks() = %q, want none", got)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	tests := []struct {
+		language string
+		n, count int
+		want     string
+	}{
+		{"python", 1, 1, "snippet.py"},
+		{"bash", 2, 3, "snippet-2.sh"},
+		{"tsx", 1, 1, "snippet.tsx"},
+		{"", 1, 1, "snippet.txt"},
+		{"objective-c", 1, 1, "snippet.txt"},
+		{"dockerfile", 1, 1, "Dockerfile"},
+		{"dockerfile", 2, 2, "Dockerfile-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Filename(CodeBlock{Language: tt.language}, tt.n, tt.count); got != tt.want {
+				t.Errorf("Filename(%q, %d, %d) = %q, want %q", tt.language, tt.n, tt.count, got, tt.want)
+			}
+		})
+	}
+}
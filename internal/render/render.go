@@ -0,0 +1,75 @@
+// Package render lays out model replies for Discord messages
+package render
+
+import "fmt"
+
+const (
+	// MessageLimit is Discord's limit for message content
+	MessageLimit = 2000
+	// EmbedDescriptionLimit is Discord's limit for an embed's description
+	EmbedDescriptionLimit = 4096
+	// EmbedFooterLimit is Discord's limit for an embed's footer text
+	EmbedFooterLimit = 2048
+	// EmbedTotalLimit is Discord's limit for all text in a message's embeds
+	EmbedTotalLimit = 6000
+)
+
+// Options controls how a reply is laid out
+type Options struct {
+	AttachOver  int    // Replies longer than this many characters are attached as a file instead (0 = never)
+	Filename    string // Name of the attached file, shown in the message that carries it
+	Footer      string // Line appended to the last message
+	Embed       bool   // Post the reply as embeds instead of message content
+	EmbedFooter string // Footer of the last embed, e.g. the model and usage
+	Escape      Escape // What to defuse in the reply's text; attachments are left as written
+}
+
+// Layout is a reply ready to post
+type Layout struct {
+	Messages    []string // Message contents (or embed descriptions), in order; buttons belong on the last one
+	Attachment  string   // Content to attach to the last message as a file, if the reply was too long
+	Embed       bool     // Messages are embed descriptions
+	EmbedFooter string   // Footer of the last embed
+	Footer      string   // Content of the last message alongside its embed
+}
+
+// Reply lays out a reply as one or more messages, or as a file attachment if it is too long
+func Reply(content string, opts Options) Layout {
+	limit := MessageLimit
+	if opts.Embed {
+		limit = EmbedDescriptionLimit
+	} else if opts.Footer != "" {
+		limit -= len([]rune(opts.Footer)) + 1
+	}
+
+	var layout Layout
+	if length := len([]rune(content)); opts.AttachOver > 0 && length > opts.AttachOver {
+		layout = Layout{
+			Messages:   []string{fmt.Sprintf("📎 This reply is %d characters long, so it's attached as `%s`.", length, opts.Filename)},
+			Attachment: content,
+		}
+	} else {
+		layout = Layout{Messages: Split(Sanitize(content, opts.Escape), limit)}
+	}
+
+	last := len(layout.Messages) - 1
+	if opts.Embed {
+		// The footer shares the message's embed text limit with the description
+		room := min(EmbedFooterLimit, EmbedTotalLimit-len([]rune(layout.Messages[last])))
+		layout.Embed = true
+		layout.EmbedFooter = truncate(opts.EmbedFooter, room)
+		layout.Footer = opts.Footer
+	} else if opts.Footer != "" {
+		layout.Messages[last] += "\n" + opts.Footer
+	}
+	return layout
+}
+
+// truncate shortens text to at most limit characters, marking the cut with an ellipsis
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,119 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+		want    []string
+	}{
+		{"fits", "Hello there", 20, []string{"Hello there"}},
+		{"paragraphs", "First paragraph here.\n\nSecond one.", 28, []string{"First paragraph here.", "Second one."}},
+		{"lines", "line one\nline two\nline three", 22, []string{"line one\nline two", "line three"}},
+		{"words", "alpha beta gamma delta", 15, []string{"alpha beta", "gamma delta"}},
+		{"hard cut", "abcdefghijklmnop", 10, []string{"abcdef", "ghijklmnop"}},
+		{"code fence reopened", "Intro\n```go\nfunc a() {}\nfunc b() {}\n```\nDone", 30, []string{
+			"Intro\n```go\nfunc a() {}\n```",
+			"```go\nfunc b() {}\n```\nDone",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Split(tt.content, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit_Limit(t *testing.T) {
+	content := strings.Repeat("Ünïcödé wörds ", 100) + "\n```python\n" + strings.Repeat("print('x')\n", 300) + "```\n" + strings.Repeat("tail ", 400)
+
+	chunks := Split(content, MessageLimit)
+	if len(chunks) < 3 {
+		t.Fatalf("Split() returned %d chunks, want at least 3", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := utf8.RuneCountInString(chunk); n > MessageLimit {
+			t.Errorf("Chunk %d has %d characters", i, n)
+		}
+		if strings.Count(chunk, "```")%2 != 0 {
+			t.Errorf("Chunk %d leaves a code block open", i)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		limit   int
+	}{
+		{"fits", "Hello there", 20},
+		{"lines", "line one\nline two\nline three", 22},
+		{"code fence reopened", "Intro\n```go\nfunc a() {}\nfunc b() {}\n```\nDone", 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Join(Split(tt.content, tt.limit)); got != tt.content {
+				t.Errorf("Join(Split()) = %q, want %q", got, tt.content)
+			}
+		})
+	}
+}
+
+func TestReply(t *testing.T) {
+	// Short replies get the footer on their only message
+	layout := Reply("Hi", Options{Footer: "-# footer"})
+	if !reflect.DeepEqual(layout.Messages, []string{"Hi\n-# footer"}) || layout.Attachment != "" {
+		t.Errorf("Reply() = %+v", layout)
+	}
+
+	// The footer fits on the last chunk
+	long := strings.Repeat("word ", 500)
+	layout = Reply(long, Options{Footer: "-# footer"})
+	last := layout.Messages[len(layout.Messages)-1]
+	if len(layout.Messages) != 2 || !strings.HasSuffix(last, "\n-# footer") || utf8.RuneCountInString(last) > MessageLimit {
+		t.Errorf("Reply() = %d message(s), last %q", len(layout.Messages), last)
+	}
+
+	// Mentions are escaped in messages but not in attachments
+	layout = Reply("Hey @everyone", Options{Escape: Escape{MassMentions: true}})
+	if layout.Messages[0] != "Hey @\u200beveryone" {
+		t.Errorf("Reply() = %q, want mentions escaped", layout.Messages[0])
+	}
+	layout = Reply("Hey @everyone", Options{AttachOver: 5, Escape: Escape{MassMentions: true}})
+	if layout.Attachment != "Hey @everyone" {
+		t.Errorf("Reply() attachment = %q, want it as written", layout.Attachment)
+	}
+
+	// Replies over the threshold are attached
+	layout = Reply(long, Options{AttachOver: 1000, Filename: "reply.md"})
+	if len(layout.Messages) != 1 || layout.Attachment != long || !strings.Contains(layout.Messages[0], "reply.md") {
+		t.Errorf("Reply() = %+v", layout)
+	}
+
+	// Embeds use the longer description limit and keep the footer out of the text
+	layout = Reply(long, Options{Embed: true, Footer: "-# footer", EmbedFooter: "GPT-4o · 120 tokens"})
+	if len(layout.Messages) != 1 || layout.Messages[0] != long || !layout.Embed {
+		t.Errorf("Reply() = %d message(s), embed %v", len(layout.Messages), layout.Embed)
+	}
+	if layout.Footer != "-# footer" || layout.EmbedFooter != "GPT-4o · 120 tokens" {
+		t.Errorf("Reply() footers = %q, %q", layout.Footer, layout.EmbedFooter)
+	}
+
+	// The embed footer is cut to fit the message's embed text limit
+	layout = Reply(strings.Repeat("x", EmbedDescriptionLimit), Options{Embed: true, EmbedFooter: strings.Repeat("y", EmbedFooterLimit)})
+	if n := utf8.RuneCountInString(layout.Messages[0]) + utf8.RuneCountInString(layout.EmbedFooter); n > EmbedTotalLimit {
+		t.Errorf("Embed text is %d characters, want at most %d", n, EmbedTotalLimit)
+	}
+}
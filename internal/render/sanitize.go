@@ -0,0 +1,29 @@
+package render
+
+import "regexp"
+
+// Escape controls what Sanitize defuses in model output
+type Escape struct {
+	MassMentions bool // @everyone, @here and role mentions
+	Invites      bool // Discord invite links
+}
+
+var (
+	massMentionPattern = regexp.MustCompile(`@(everyone|here)\b`)
+	roleMentionPattern = regexp.MustCompile(`<@&(\d+)>`)
+	invitePattern      = regexp.MustCompile(`(?i)(?:https?://)?(?:www\.)?(?:discord\.gg|discord(?:app)?\.com/invite)/[a-z0-9-]+`)
+)
+
+// Sanitize defuses mentions and invite links in model output before it is posted
+// Mass and role mentions get a zero-width space so they show as text; invites are shown as code.
+// Allowed mentions already keep replies from pinging; this keeps them from looking like pings.
+func Sanitize(content string, escape Escape) string {
+	if escape.MassMentions {
+		content = massMentionPattern.ReplaceAllString(content, "@\u200b$1")
+		content = roleMentionPattern.ReplaceAllString(content, "<@\u200b&$1>")
+	}
+	if escape.Invites {
+		content = invitePattern.ReplaceAllString(content, "`$0`")
+	}
+	return content
+}
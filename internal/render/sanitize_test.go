@@ -0,0 +1,26 @@
+package render
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		escape  Escape
+		want    string
+	}{
+		{"nothing escaped", "Hey @everyone, join discord.gg/abc", Escape{}, "Hey @everyone, join discord.gg/abc"},
+		{"mass mentions", "Hey @everyone and @here, ping <@&123>", Escape{MassMentions: true}, "Hey @\u200beveryone and @\u200bhere, ping <@\u200b&123>"},
+		{"user mentions kept", "Thanks <@456>!", Escape{MassMentions: true}, "Thanks <@456>!"},
+		{"invites", "Join https://discord.gg/abc-123 or discord.com/invite/xyz", Escape{Invites: true}, "Join `https://discord.gg/abc-123` or `discord.com/invite/xyz`"},
+		{"other links", "See https://discord.com/developers", Escape{Invites: true}, "See https://discord.com/developers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.content, tt.escape); got != tt.want {
+				t.Errorf("Sanitize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
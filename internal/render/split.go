@@ -0,0 +1,89 @@
+package render
+
+import "strings"
+
+// fenceClose closes a code fence left open at the end of a chunk
+const fenceClose = "\n```"
+
+// Split breaks content into chunks of at most limit characters
+// Chunks end at paragraph breaks where possible, then at line breaks, then at spaces.
+// A code block that is split is closed at the end of its chunk and reopened, with its
+// language, at the start of the next.
+func Split(content string, limit int) []string {
+	var chunks []string
+	text := []rune(content)
+	for {
+		if len(text) <= limit {
+			return append(chunks, string(text))
+		}
+
+		// Leave room to close a code block
+		cut := cutPoint(text, limit-len([]rune(fenceClose)))
+		chunk := strings.TrimRight(string(text[:cut]), " \n")
+		rest := strings.TrimLeft(string(text[cut:]), " \n")
+
+		if fence := openFence(chunk); fence != "" {
+			chunk += fenceClose
+			rest = fence + "\n" + rest
+		}
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		text = []rune(rest)
+	}
+}
+
+// cutPoint returns where to end a chunk of text of at most max characters
+func cutPoint(text []rune, max int) int {
+	window := string(text[:max])
+
+	// Prefer a break in the second half of the window so chunks don't get too short
+	for _, sep := range []string{"\n\n", "\n", " "} {
+		if i := strings.LastIndex(window, sep); i > 0 {
+			if n := len([]rune(window[:i])); n >= max/2 {
+				return n
+			}
+		}
+	}
+	return max
+}
+
+// openFence returns the opening line of the code block left open at the end of text, or ""
+// Reopened fences are shortened to just the language so they can't keep a chunk from making progress.
+func openFence(text string) string {
+	open := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "```") {
+			continue
+		}
+		if open != "" {
+			open = ""
+			continue
+		}
+		open = "```"
+		if fields := strings.Fields(strings.TrimPrefix(trimmed, "```")); len(fields) > 0 && len(fields[0]) <= 20 {
+			open += fields[0]
+		}
+	}
+	return open
+}
+
+// Join puts the chunks of a split reply back together
+// Code blocks closed and reopened between chunks are merged again; other breaks become a line break.
+func Join(chunks []string) string {
+	joined := ""
+	for n, chunk := range chunks {
+		if n == 0 {
+			joined = chunk
+			continue
+		}
+		head := strings.TrimSuffix(joined, fenceClose)
+		if fence := openFence(head); fence != "" && head != joined && strings.HasPrefix(chunk, fence+"\n") {
+			joined = head + "\n" + strings.TrimPrefix(chunk, fence+"\n")
+			continue
+		}
+		joined += "\n" + chunk
+	}
+	return joined
+}
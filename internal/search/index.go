@@ -0,0 +1,57 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+// ErrEmptyQuery is returned when a query has no searchable terms
+var ErrEmptyQuery = errors.New("query has no searchable words")
+
+// snippetWidth is the approximate length of result snippets
+const snippetWidth = 160
+
+// Document is a conversation message to index
+type Document struct {
+	GuildID  string
+	ThreadID string
+	Role     string
+	Content  string
+	Time     time.Time
+}
+
+// Hit is the best match for a query within one thread
+type Hit struct {
+	ThreadID string
+	Role     string
+	Snippet  string
+	Time     time.Time
+}
+
+// Index is a full-text index of conversation messages
+type Index interface {
+	// Name identifies the backend, e.g. for logs
+	Name() string
+	// Add indexes a message
+	Add(ctx context.Context, doc Document) error
+	// RemoveThread drops all of a thread's messages from the index
+	RemoveThread(ctx context.Context, guildID, threadID string) error
+	// Search returns up to limit threads in a guild matching every term of the query, best first
+	Search(ctx context.Context, guildID, query string, limit int) ([]Hit, error)
+}
+
+// New returns a RediSearch-backed index when the module is available, otherwise the built-in index
+// Indexed messages expire after ttl, like the conversations they belong to
+func New(ctx context.Context, client *storage.Client, ttl time.Duration) (Index, error) {
+	if client.Redis().FT_List(ctx).Err() == nil {
+		index, err := NewRediSearchIndex(ctx, client, ttl)
+		if err != nil {
+			return nil, err
+		}
+		return index, nil
+	}
+	return NewRedisIndex(client, ttl), nil
+}
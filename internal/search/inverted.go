@@ -0,0 +1,179 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+// maxThreadText caps the number of messages kept per thread for snippets
+const maxThreadText = 500
+
+// indexedText is a message's text as stored for snippets
+type indexedText struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	Time    int64  `json:"time"`
+}
+
+// RedisIndex is a built-in inverted index stored in plain Redis keys
+// Each term maps to a sorted set of threads (scored by the latest matching message) and each
+// thread keeps its message text for snippets and the set of its terms for removal.
+type RedisIndex struct {
+	client *storage.Client
+	ttl    time.Duration
+}
+
+// NewRedisIndex creates a built-in inverted index
+func NewRedisIndex(client *storage.Client, ttl time.Duration) *RedisIndex {
+	return &RedisIndex{client: client, ttl: ttl}
+}
+
+// Name returns the backend name
+func (x *RedisIndex) Name() string {
+	return "builtin"
+}
+
+// Add indexes a message
+func (x *RedisIndex) Add(ctx context.Context, doc Document) error {
+	terms := Tokenize(doc.Content)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(indexedText{Role: doc.Role, Content: doc.Content, Time: doc.Time.Unix()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal indexed text: %w", err)
+	}
+
+	keys := x.client.Keys()
+	termsKey := keys.SearchThreadTerms(doc.GuildID, doc.ThreadID)
+	textKey := keys.SearchThreadText(doc.GuildID, doc.ThreadID)
+
+	pipe := x.client.Redis().Pipeline()
+	for _, term := range terms {
+		termKey := keys.SearchTerm(doc.GuildID, term)
+		pipe.ZAdd(ctx, termKey, redis.Z{Score: float64(doc.Time.Unix()), Member: doc.ThreadID})
+		pipe.Expire(ctx, termKey, x.ttl)
+		pipe.SAdd(ctx, termsKey, term)
+	}
+	pipe.Expire(ctx, termsKey, x.ttl)
+	pipe.RPush(ctx, textKey, data)
+	pipe.LTrim(ctx, textKey, -maxThreadText, -1)
+	pipe.Expire(ctx, textKey, x.ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveThread drops all of a thread's messages from the index
+func (x *RedisIndex) RemoveThread(ctx context.Context, guildID, threadID string) error {
+	keys := x.client.Keys()
+	termsKey := keys.SearchThreadTerms(guildID, threadID)
+
+	terms, err := x.client.Redis().SMembers(ctx, termsKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get indexed terms: %w", err)
+	}
+
+	pipe := x.client.Redis().Pipeline()
+	for _, term := range terms {
+		pipe.ZRem(ctx, keys.SearchTerm(guildID, term), threadID)
+	}
+	pipe.Del(ctx, termsKey, keys.SearchThreadText(guildID, threadID))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove thread from index: %w", err)
+	}
+
+	return nil
+}
+
+// Search returns up to limit threads matching every term of the query, most recently matched first
+func (x *RedisIndex) Search(ctx context.Context, guildID, query string, limit int) ([]Hit, error) {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	keys := x.client.Keys()
+	termKeys := make([]string, len(terms))
+	for i, term := range terms {
+		termKeys[i] = keys.SearchTerm(guildID, term)
+	}
+
+	// Threads containing every term, scored by their latest match
+	matches, err := x.client.Redis().ZInterWithScores(ctx, &redis.ZStore{Keys: termKeys, Aggregate: "MAX"}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	hits := make([]Hit, 0, limit)
+	for i := len(matches) - 1; i >= 0 && len(hits) < limit; i-- {
+		threadID := matches[i].Member.(string)
+
+		texts, err := x.client.Redis().LRange(ctx, keys.SearchThreadText(guildID, threadID), 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load indexed text: %w", err)
+		}
+		if len(texts) == 0 {
+			// The thread's text expired before its terms; drop the stale postings
+			if err := x.RemoveThread(ctx, guildID, threadID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if hit, ok := bestHit(threadID, texts, terms); ok {
+			hits = append(hits, hit)
+		}
+	}
+
+	return hits, nil
+}
+
+// bestHit picks the latest message of a thread that contains every term
+// Terms can be spread over several messages, so fall back to the latest message with any term
+func bestHit(threadID string, texts []string, terms []string) (Hit, bool) {
+	var fallback *indexedText
+	for i := len(texts) - 1; i >= 0; i-- {
+		var text indexedText
+		if err := json.Unmarshal([]byte(texts[i]), &text); err != nil {
+			continue
+		}
+
+		if ContainsAll(text.Content, terms) {
+			return newHit(threadID, text, terms), true
+		}
+		if fallback == nil {
+			for _, term := range terms {
+				if ContainsAll(text.Content, []string{term}) {
+					fallback = &text
+					break
+				}
+			}
+		}
+	}
+
+	if fallback == nil {
+		return Hit{}, false
+	}
+	return newHit(threadID, *fallback, terms), true
+}
+
+// newHit builds a hit from an indexed message
+func newHit(threadID string, text indexedText, terms []string) Hit {
+	return Hit{
+		ThreadID: threadID,
+		Role:     text.Role,
+		Snippet:  Snippet(text.Content, terms, snippetWidth),
+		Time:     time.Unix(text.Time, 0),
+	}
+}
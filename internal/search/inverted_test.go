@@ -0,0 +1,76 @@
+package search
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/s33g/discord-prompter/internal/config"
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+func getTestClient(t *testing.T) *storage.Client {
+	t.Helper()
+
+	cfg := config.RedisConfig{
+		Address:   "localhost:6379",
+		DB:        15,
+		KeyPrefix: "test:",
+	}
+
+	client, err := storage.NewClient(cfg)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+
+	// Clean test database
+	ctx := context.Background()
+	client.Redis().FlushDB(ctx)
+
+	return client
+}
+
+func TestRedisIndex_Search(t *testing.T) {
+	client := getTestClient(t)
+	defer client.Close()
+
+	index := NewRedisIndex(client, time.Hour)
+	ctx := context.Background()
+	now := time.Now()
+
+	docs := []Document{
+		{GuildID: "g1", ThreadID: "old", Role: "user", Content: "Terraform state lock is stuck", Time: now.Add(-2 * time.Hour)},
+		{GuildID: "g1", ThreadID: "new", Role: "user", Content: "How do I release a Terraform lock?", Time: now.Add(-time.Hour)},
+		{GuildID: "g1", ThreadID: "other", Role: "user", Content: "Kubernetes state", Time: now},
+		{GuildID: "g2", ThreadID: "elsewhere", Role: "user", Content: "Terraform lock", Time: now},
+	}
+	for _, doc := range docs {
+		if err := index.Add(ctx, doc); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	hits, err := index.Search(ctx, "g1", "terraform LOCK", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 2 || hits[0].ThreadID != "new" || hits[1].ThreadID != "old" {
+		t.Fatalf("Search() = %+v, want [new old]", hits)
+	}
+	if hits[0].Snippet == "" {
+		t.Error("Expected a snippet")
+	}
+
+	// Removed threads no longer match
+	if err := index.RemoveThread(ctx, "g1", "new"); err != nil {
+		t.Fatalf("RemoveThread() error = %v", err)
+	}
+	hits, _ = index.Search(ctx, "g1", "terraform lock", 10)
+	if len(hits) != 1 || hits[0].ThreadID != "old" {
+		t.Errorf("Search() after remove = %+v, want [old]", hits)
+	}
+
+	if _, err := index.Search(ctx, "g1", "the", 10); err != ErrEmptyQuery {
+		t.Errorf("Search() with only stop words error = %v, want ErrEmptyQuery", err)
+	}
+}
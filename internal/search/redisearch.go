@@ -0,0 +1,166 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/s33g/discord-prompter/internal/storage"
+)
+
+// searchCandidates is how many matching messages are fetched to find distinct threads
+const searchCandidates = 200
+
+// RediSearchIndex indexes messages as hashes in a RediSearch index
+type RediSearchIndex struct {
+	client *storage.Client
+	ttl    time.Duration
+	name   string
+}
+
+// NewRediSearchIndex creates the RediSearch index if it doesn't exist yet
+func NewRediSearchIndex(ctx context.Context, client *storage.Client, ttl time.Duration) (*RediSearchIndex, error) {
+	x := &RediSearchIndex{
+		client: client,
+		ttl:    ttl,
+		name:   client.Keys().SearchIndex(),
+	}
+
+	indexes, err := client.Redis().FT_List(ctx).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search indexes: %w", err)
+	}
+	for _, name := range indexes {
+		if name == x.name {
+			return x, nil
+		}
+	}
+
+	err = client.Redis().FTCreate(ctx, x.name,
+		&redis.FTCreateOptions{OnHash: true, Prefix: []interface{}{client.Keys().SearchDocPrefix()}},
+		&redis.FieldSchema{FieldName: "content", FieldType: redis.SearchFieldTypeText},
+		&redis.FieldSchema{FieldName: "guild_id", FieldType: redis.SearchFieldTypeTag},
+		&redis.FieldSchema{FieldName: "thread_id", FieldType: redis.SearchFieldTypeTag},
+		&redis.FieldSchema{FieldName: "role", FieldType: redis.SearchFieldTypeTag},
+		&redis.FieldSchema{FieldName: "time", FieldType: redis.SearchFieldTypeNumeric, Sortable: true},
+	).Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	return x, nil
+}
+
+// Name returns the backend name
+func (x *RediSearchIndex) Name() string {
+	return "redisearch"
+}
+
+// Add indexes a message
+func (x *RediSearchIndex) Add(ctx context.Context, doc Document) error {
+	// Messages indexed together share doc.Time, so key documents by when they are added
+	key := x.client.Keys().SearchDoc(doc.GuildID, doc.ThreadID, time.Now().UnixNano())
+
+	pipe := x.client.Redis().Pipeline()
+	pipe.HSet(ctx, key,
+		"content", doc.Content,
+		"guild_id", doc.GuildID,
+		"thread_id", doc.ThreadID,
+		"role", doc.Role,
+		"time", doc.Time.Unix(),
+	)
+	pipe.Expire(ctx, key, x.ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveThread drops all of a thread's messages from the index
+func (x *RediSearchIndex) RemoveThread(ctx context.Context, guildID, threadID string) error {
+	// Document keys are scoped by guild and thread, so no query is needed
+	pattern := x.client.Keys().SearchDocPrefix() + guildID + ":" + threadID + ":*"
+
+	var keys []string
+	iter := x.client.Redis().Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to find indexed messages: %w", err)
+	}
+
+	if len(keys) > 0 {
+		if err := x.client.Redis().Del(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("failed to remove thread from index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Search returns up to limit threads matching every term of the query, most relevant first
+func (x *RediSearchIndex) Search(ctx context.Context, guildID, query string, limit int) ([]Hit, error) {
+	terms := Tokenize(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	// Tokenized terms only contain letters and digits, so they need no escaping
+	q := fmt.Sprintf("@guild_id:{%s} @content:(%s)", escapeTag(guildID), strings.Join(terms, " "))
+
+	result, err := x.client.Redis().FTSearchWithArgs(ctx, x.name, q, &redis.FTSearchOptions{
+		Return: []redis.FTSearchReturn{
+			{FieldName: "thread_id"},
+			{FieldName: "role"},
+			{FieldName: "content"},
+			{FieldName: "time"},
+		},
+		Limit:          searchCandidates,
+		DialectVersion: 2,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search index: %w", err)
+	}
+
+	// Keep the most relevant message of each thread
+	seen := make(map[string]bool)
+	hits := make([]Hit, 0, limit)
+	for _, doc := range result.Docs {
+		threadID := doc.Fields["thread_id"]
+		if threadID == "" || seen[threadID] {
+			continue
+		}
+		seen[threadID] = true
+
+		unix, _ := strconv.ParseInt(doc.Fields["time"], 10, 64)
+		hits = append(hits, newHit(threadID, indexedText{
+			Role:    doc.Fields["role"],
+			Content: doc.Fields["content"],
+			Time:    unix,
+		}, terms))
+
+		if len(hits) == limit {
+			break
+		}
+	}
+
+	return hits, nil
+}
+
+// escapeTag escapes punctuation in a TAG query value
+func escapeTag(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if !isWordRune(r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,156 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minTermLength is the shortest term that is indexed
+const minTermLength = 2
+
+// stopWords are common English words left out of the index
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "for": true, "if": true, "in": true, "into": true, "is": true,
+	"it": true, "no": true, "not": true, "of": true, "on": true, "or": true, "such": true,
+	"that": true, "the": true, "their": true, "then": true, "there": true, "these": true,
+	"they": true, "this": true, "to": true, "was": true, "will": true, "with": true,
+}
+
+// Tokenize splits text into unique lowercase search terms
+// Punctuation separates terms and stop words are dropped, so "Terraform's state-lock" becomes
+// [terraform s state lock] minus anything too short.
+func Tokenize(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+
+	for _, word := range splitWords(text) {
+		if utf8.RuneCountInString(word) < minTermLength || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+
+	return terms
+}
+
+// ContainsAll reports whether text contains every term
+func ContainsAll(text string, terms []string) bool {
+	words := make(map[string]bool)
+	for _, word := range splitWords(text) {
+		words[word] = true
+	}
+	for _, term := range terms {
+		if !words[term] {
+			return false
+		}
+	}
+	return true
+}
+
+// Snippet returns an excerpt of text around the first matching term, with matches in bold
+// Width is the approximate number of characters to keep.
+func Snippet(text string, terms []string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	lower := []rune(toLower(text))
+
+	// Find the earliest match
+	first := -1
+	for _, term := range terms {
+		if idx := indexWord(lower, []rune(term)); idx >= 0 && (first < 0 || idx < first) {
+			first = idx
+		}
+	}
+
+	start := 0
+	if first > width/3 {
+		start = first - width/3
+	}
+	end := start + width
+	if end > len(runes) {
+		// Near the end of the text, show more of what comes before the match
+		end = len(runes)
+		start = max(0, end-width)
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	sb.WriteString(highlight(runes[start:end], lower[start:end], terms))
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+// splitWords lowercases text and splits it on anything that isn't a letter or digit
+func splitWords(text string) []string {
+	return strings.FieldsFunc(toLower(text), func(r rune) bool {
+		return !isWordRune(r)
+	})
+}
+
+// toLower lowercases text rune by rune, so rune offsets match the original text
+func toLower(text string) string {
+	return strings.Map(unicode.ToLower, text)
+}
+
+// highlight wraps whole-word matches of terms in Markdown bold
+func highlight(runes, lower []rune, terms []string) string {
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		matched := 0
+		if i == 0 || !isWordRune(lower[i-1]) {
+			for _, term := range terms {
+				t := []rune(term)
+				if hasWordAt(lower, t, i) && len(t) > matched {
+					matched = len(t)
+				}
+			}
+		}
+
+		if matched > 0 {
+			sb.WriteString("**")
+			sb.WriteString(string(runes[i : i+matched]))
+			sb.WriteString("**")
+			i += matched
+			continue
+		}
+
+		sb.WriteRune(runes[i])
+		i++
+	}
+	return sb.String()
+}
+
+// indexWord returns the index of the first whole-word occurrence of term in text, or -1
+func indexWord(text, term []rune) int {
+	for i := range text {
+		if (i == 0 || !isWordRune(text[i-1])) && hasWordAt(text, term, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasWordAt reports whether term occurs at position i of text and ends at a word boundary
+func hasWordAt(text, term []rune, i int) bool {
+	if i+len(term) > len(text) {
+		return false
+	}
+	for j, r := range term {
+		if text[i+j] != r {
+			return false
+		}
+	}
+	return i+len(term) == len(text) || !isWordRune(text[i+len(term)])
+}
+
+// isWordRune reports whether r is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
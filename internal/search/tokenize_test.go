@@ -0,0 +1,61 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"punctuation and case", "Terraform's state-lock!", []string{"terraform", "state", "lock"}},
+		{"stop words and duplicates", "the lock and the LOCK", []string{"lock"}},
+		{"digits", "Go 1.23 on k8s", []string{"go", "23", "k8s"}},
+		{"unicode", "Größe über alles", []string{"größe", "über", "alles"}},
+		{"nothing searchable", "a to the !", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	text := "How do I release the Terraform state lock?"
+
+	if !ContainsAll(text, []string{"terraform", "lock"}) {
+		t.Error("Expected all terms to match")
+	}
+	if ContainsAll(text, []string{"terraform", "locks"}) {
+		t.Error("Partial words should not match")
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("filler ", 40) + "then the Terraform state lock got stuck " + strings.Repeat("more ", 40)
+
+	got := Snippet(text, []string{"terraform", "lock"}, 80)
+
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Expected ellipses around a mid-text snippet, got %q", got)
+	}
+	if !strings.Contains(got, "**Terraform**") || !strings.Contains(got, "**lock**") {
+		t.Errorf("Expected highlighted terms, got %q", got)
+	}
+}
+
+func TestSnippet_ShortText(t *testing.T) {
+	got := Snippet("Unlock the lock", []string{"lock"}, 80)
+
+	if got != "Unlock the **lock**" {
+		t.Errorf("Snippet() = %q, want only the whole word highlighted", got)
+	}
+}
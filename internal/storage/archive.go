@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/s33g/discord-prompter/internal/config"
+)
+
+// ErrNotArchived is returned when a conversation has no archived copy
+var ErrNotArchived = errors.New("conversation not archived")
+
+// ArchiveRecord is a durable copy of a conversation's Redis state
+// Fields mirror the conversation hash and Messages the raw JSON entries of the message list
+type ArchiveRecord struct {
+	GuildID    string
+	ThreadID   string
+	Fields     map[string]string
+	Messages   []string
+	ArchivedAt time.Time
+}
+
+// Archive stores conversations beyond their Redis TTL
+type Archive interface {
+	// Save inserts or replaces the archived copy of a conversation
+	Save(ctx context.Context, record ArchiveRecord) error
+	// Load returns the archived copy of a conversation, or ErrNotArchived
+	Load(ctx context.Context, guildID, threadID string) (*ArchiveRecord, error)
+	// Delete removes the archived copy of a conversation
+	Delete(ctx context.Context, guildID, threadID string) error
+	// Close releases the archive's connections
+	Close() error
+}
+
+// NewArchive creates the archive backend configured in cfg
+// Returns nil when archiving is disabled
+func NewArchive(cfg config.ArchiveConfig) (Archive, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	dsn := os.Getenv(cfg.DSNEnv)
+	if dsn == "" {
+		return nil, fmt.Errorf("archive connection string not set: %s", cfg.DSNEnv)
+	}
+
+	switch cfg.Driver {
+	case "postgres":
+		archive, err := NewPostgresArchive(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return archive, nil
+	default:
+		return nil, fmt.Errorf("unknown archive driver: %s", cfg.Driver)
+	}
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Keys generates Redis keys with consistent naming
@@ -19,11 +20,80 @@ func (k *Keys) Conversation(guildID, threadID string) string {
 	return fmt.Sprintf("%s%s:conversation:%s", k.prefix, guildID, threadID)
 }
 
+// ConversationPattern returns a SCAN pattern matching all conversation metadata keys
+func (k *Keys) ConversationPattern() string {
+	return k.prefix + "*:conversation:*"
+}
+
+// ParseConversation extracts the guild and thread IDs from a conversation metadata key
+func (k *Keys) ParseConversation(key string) (guildID, threadID string, ok bool) {
+	rest, found := strings.CutPrefix(key, k.prefix)
+	if !found {
+		return "", "", false
+	}
+	guildID, threadID, found = strings.Cut(rest, ":conversation:")
+	if !found || guildID == "" || threadID == "" {
+		return "", "", false
+	}
+	return guildID, threadID, true
+}
+
 // Messages returns the key for message history
 func (k *Keys) Messages(guildID, threadID string) string {
 	return fmt.Sprintf("%s%s:messages:%s", k.prefix, guildID, threadID)
 }
 
+// ConversationLock returns the key for the lock held while a conversation turn is processed
+func (k *Keys) ConversationLock(guildID, threadID string) string {
+	return fmt.Sprintf("%s%s:lock:%s", k.prefix, guildID, threadID)
+}
+
+// GuildConversations returns the key for a guild's conversation index, scored by last activity
+func (k *Keys) GuildConversations(guildID string) string {
+	return fmt.Sprintf("%s%s:conversations", k.prefix, guildID)
+}
+
+// UserConversations returns the key for a user's conversation index, scored by last activity
+func (k *Keys) UserConversations(guildID, userID string) string {
+	return fmt.Sprintf("%s%s:user_conversations:%s", k.prefix, guildID, userID)
+}
+
+// Forks returns the key for the set of threads forked from a conversation
+func (k *Keys) Forks(guildID, threadID string) string {
+	return fmt.Sprintf("%s%s:forks:%s", k.prefix, guildID, threadID)
+}
+
+// SearchTerm returns the key for the threads containing a search term, scored by last match
+func (k *Keys) SearchTerm(guildID, term string) string {
+	return fmt.Sprintf("%s%s:search:term:%s", k.prefix, guildID, term)
+}
+
+// SearchThreadTerms returns the key for the set of terms indexed for a thread
+func (k *Keys) SearchThreadTerms(guildID, threadID string) string {
+	return fmt.Sprintf("%s%s:search:terms:%s", k.prefix, guildID, threadID)
+}
+
+// SearchThreadText returns the key for the indexed message text of a thread, used for snippets
+func (k *Keys) SearchThreadText(guildID, threadID string) string {
+	return fmt.Sprintf("%s%s:search:text:%s", k.prefix, guildID, threadID)
+}
+
+// SearchIndex returns the name of the RediSearch index
+func (k *Keys) SearchIndex() string {
+	return k.prefix + "search"
+}
+
+// SearchDocPrefix returns the key prefix of documents in the RediSearch index
+// RediSearch indexes by key prefix, so these keys aren't scoped by guild like the others
+func (k *Keys) SearchDocPrefix() string {
+	return k.prefix + "search:doc:"
+}
+
+// SearchDoc returns the key for a message document in the RediSearch index
+func (k *Keys) SearchDoc(guildID, threadID string, seq int64) string {
+	return fmt.Sprintf("%s%s:%s:%d", k.SearchDocPrefix(), guildID, threadID, seq)
+}
+
 // RateLimitMinute returns the key for per-minute rate limiting
 func (k *Keys) RateLimitMinute(guildID, userID string) string {
 	return fmt.Sprintf("%s%s:ratelimit:%s:minute", k.prefix, guildID, userID)
@@ -44,6 +114,11 @@ func (k *Keys) Usage(guildID, userID, date string) string {
 	return fmt.Sprintf("%s%s:usage:%s:%s", k.prefix, guildID, userID, date)
 }
 
+// ModerationAudit returns the key for a guild's moderation audit log
+func (k *Keys) ModerationAudit(guildID string) string {
+	return fmt.Sprintf("%s%s:moderation:audit", k.prefix, guildID)
+}
+
 // Prompts returns the key for guild system prompts
 func (k *Keys) Prompts(guildID string) string {
 	return fmt.Sprintf("%s%s:prompts", k.prefix, guildID)
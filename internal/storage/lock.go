@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLockTimeout is returned when a lock isn't released before the context is done
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// ErrLockLost is returned when extending a lock that expired and may have been taken by someone else
+var ErrLockLost = errors.New("lock no longer held")
+
+// lockRetryInterval is how often a held lock is retried
+const lockRetryInterval = 100 * time.Millisecond
+
+// unlockScript deletes a lock only if it is still held by the same token
+const unlockScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('DEL', KEYS[1])
+end
+return 0
+`
+
+// extendScript resets a lock's expiry only if it is still held by the same token
+const extendScript = `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+    return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`
+
+// Lock is a lease on a Redis key, held by one process at a time across replicas
+type Lock struct {
+	client *Client
+	key    string
+	token  string
+}
+
+// Lock waits until it holds the lock on key, or until ctx is done
+// The lock expires after ttl in case its holder dies without unlocking it.
+func (c *Client) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	for {
+		ok, err := c.rdb.SetNX(ctx, key, token, ttl).Result()
+		if err != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+		if ok {
+			return &Lock{client: c, key: key, token: token}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrLockTimeout
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Unlock releases the lock, unless it expired and was taken by someone else
+func (l *Lock) Unlock(ctx context.Context) error {
+	if err := l.client.rdb.Eval(ctx, unlockScript, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// Extend resets the lock to expire ttl from now, for holders that need it longer than first planned
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	extended, err := l.client.rdb.Eval(ctx, extendScript, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to extend lock: %w", err)
+	}
+	if extended == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
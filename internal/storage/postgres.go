@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq" // Postgres driver
+)
+
+// postgresSchema creates the archive tables if they don't exist
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS archived_conversations (
+	guild_id    TEXT NOT NULL,
+	thread_id   TEXT NOT NULL,
+	fields      JSONB NOT NULL,
+	archived_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (guild_id, thread_id)
+);
+CREATE TABLE IF NOT EXISTS archived_messages (
+	guild_id  TEXT NOT NULL,
+	thread_id TEXT NOT NULL,
+	position  INTEGER NOT NULL,
+	data      TEXT NOT NULL,
+	PRIMARY KEY (guild_id, thread_id, position),
+	FOREIGN KEY (guild_id, thread_id) REFERENCES archived_conversations (guild_id, thread_id) ON DELETE CASCADE
+);`
+
+// PostgresArchive archives conversations in PostgreSQL
+type PostgresArchive struct {
+	db *sql.DB
+}
+
+// NewPostgresArchive connects to PostgreSQL and creates the archive tables
+func NewPostgresArchive(dsn string) (*PostgresArchive, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive database: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Test connection
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to archive database: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, postgresSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create archive tables: %w", err)
+	}
+
+	return &PostgresArchive{db: db}, nil
+}
+
+// Save inserts or replaces the archived copy of a conversation
+func (a *PostgresArchive) Save(ctx context.Context, record ArchiveRecord) error {
+	fields, err := json.Marshal(record.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation fields: %w", err)
+	}
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin archive transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO archived_conversations (guild_id, thread_id, fields, archived_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (guild_id, thread_id) DO UPDATE SET fields = EXCLUDED.fields, archived_at = EXCLUDED.archived_at`,
+		record.GuildID, record.ThreadID, fields, record.ArchivedAt)
+	if err != nil {
+		return fmt.Errorf("failed to archive conversation: %w", err)
+	}
+
+	// Replace the message history wholesale; histories are capped so this stays small
+	if _, err := tx.ExecContext(ctx, `DELETE FROM archived_messages WHERE guild_id = $1 AND thread_id = $2`, record.GuildID, record.ThreadID); err != nil {
+		return fmt.Errorf("failed to clear archived messages: %w", err)
+	}
+	for pos, data := range record.Messages {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO archived_messages (guild_id, thread_id, position, data)
+			VALUES ($1, $2, $3, $4)`,
+			record.GuildID, record.ThreadID, pos, data)
+		if err != nil {
+			return fmt.Errorf("failed to archive message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit archive transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Load returns the archived copy of a conversation
+func (a *PostgresArchive) Load(ctx context.Context, guildID, threadID string) (*ArchiveRecord, error) {
+	record := ArchiveRecord{GuildID: guildID, ThreadID: threadID}
+
+	var fields []byte
+	err := a.db.QueryRowContext(ctx, `
+		SELECT fields, archived_at FROM archived_conversations
+		WHERE guild_id = $1 AND thread_id = $2`,
+		guildID, threadID).Scan(&fields, &record.ArchivedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotArchived
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archived conversation: %w", err)
+	}
+
+	if err := json.Unmarshal(fields, &record.Fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal conversation fields: %w", err)
+	}
+
+	rows, err := a.db.QueryContext(ctx, `
+		SELECT data FROM archived_messages
+		WHERE guild_id = $1 AND thread_id = $2
+		ORDER BY position`,
+		guildID, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archived messages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read archived message: %w", err)
+		}
+		record.Messages = append(record.Messages, data)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load archived messages: %w", err)
+	}
+
+	return &record, nil
+}
+
+// Delete removes the archived copy of a conversation
+func (a *PostgresArchive) Delete(ctx context.Context, guildID, threadID string) error {
+	// Messages are removed by the cascading foreign key
+	_, err := a.db.ExecContext(ctx, `DELETE FROM archived_conversations WHERE guild_id = $1 AND thread_id = $2`, guildID, threadID)
+	if err != nil {
+		return fmt.Errorf("failed to delete archived conversation: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database connection
+func (a *PostgresArchive) Close() error {
+	return a.db.Close()
+}
@@ -27,6 +27,8 @@ func NewClient(cfg config.RedisConfig) (*Client, error) {
 		Addr:     cfg.Address,
 		Password: password,
 		DB:       cfg.DB,
+		// go-redis only parses RediSearch replies over RESP2
+		Protocol: 2,
 	})
 
 	// Test connection